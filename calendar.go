@@ -0,0 +1,96 @@
+package loancalc
+
+import "time"
+
+// IsHoliday 让 HolidayFunc 也可作为 HolidayProvider 使用
+func (f HolidayFunc) IsHoliday(t time.Time) bool { return f(t) }
+
+// BusinessCalendar 基于 HolidayProvider 的营业日运算，用于宽限期、结算滞后和 BUS/252 计息
+type BusinessCalendar struct {
+	HolidayProvider
+}
+
+// NewBusinessCalendar hp 为空时使用 Config.Holiday
+func NewBusinessCalendar(hp HolidayProvider) BusinessCalendar {
+	if hp == nil {
+		hp = cfg.Holiday
+	}
+	return BusinessCalendar{HolidayProvider: hp}
+}
+
+// IsBusinessDay 是否为营业日
+func (c BusinessCalendar) IsBusinessDay(t time.Time) bool {
+	return !c.IsHoliday(t)
+}
+
+// NextBusinessDay 返回 t 之后（不含 t）的第一个营业日
+func (c BusinessCalendar) NextBusinessDay(t time.Time) time.Time {
+	t = t.AddDate(0, 0, 1)
+	for c.IsHoliday(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// PrevBusinessDay 返回 t 之前（不含 t）的最后一个营业日
+func (c BusinessCalendar) PrevBusinessDay(t time.Time) time.Time {
+	t = t.AddDate(0, 0, -1)
+	for c.IsHoliday(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// AddBusinessDays 加上 n 个营业日，n 为负时向前推算；n 为 0 且 t 不是营业日时顺延到下一个营业日
+func (c BusinessCalendar) AddBusinessDays(t time.Time, n int) time.Time {
+	if n == 0 {
+		if c.IsHoliday(t) {
+			return c.NextBusinessDay(t)
+		}
+		return t
+	}
+	for ; n > 0; n-- {
+		t = c.NextBusinessDay(t)
+	}
+	for ; n < 0; n++ {
+		t = c.PrevBusinessDay(t)
+	}
+	return t
+}
+
+// BusinessDaysBetween 统计 [start, end) 内的营业日天数，end 早于 start 时返回负数
+func (c BusinessCalendar) BusinessDaysBetween(start, end time.Time) int {
+	sign := 1
+	if CompareDate(end, start) < 0 {
+		start, end = end, start
+		sign = -1
+	}
+	days := 0
+	for t := start; CompareDate(t, end) < 0; t = t.AddDate(0, 0, 1) {
+		if !c.IsHoliday(t) {
+			days++
+		}
+	}
+	return days * sign
+}
+
+// HolidaysBetween 列出 [start, end] 内的非营业日；onlyHoliday 为 true 时只列法定节假日，不含普通周末
+func (c BusinessCalendar) HolidaysBetween(start, end time.Time, onlyHoliday bool) []time.Time {
+	var days []time.Time
+	for t := start; CompareDate(t, end) <= 0; t = t.AddDate(0, 0, 1) {
+		k := kindOf(c.HolidayProvider, t)
+		if k == DayHoliday || (!onlyHoliday && !k.IsBusinessDay()) {
+			days = append(days, t)
+		}
+	}
+	return days
+}
+
+// GraceDeadline 返回还款日 due 的宽限期截止日：due 之后 GraceDay 个自然日，截止日遇非营业日顺延到下一个营业日。
+// 截止日（含）之前还款不算逾期
+func (s *Product) GraceDeadline(due time.Time) time.Time {
+	if s.GraceDay <= 0 {
+		return due
+	}
+	return NewBusinessCalendar(s.holidays()).AddBusinessDays(due.AddDate(0, 0, s.GraceDay), 0)
+}
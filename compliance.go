@@ -0,0 +1,179 @@
+package loancalc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// CostCeiling 综合年化成本上限，如司法保护的 24%/36% 或 4 倍 LPR
+type CostCeiling struct {
+	Rate            decimal.Decimal // 固定上限，为 0 时不启用
+	LPRMultiple     decimal.Decimal // 基准利率倍数上限，为 0 时不启用；与 Rate 同时配置时取较低者
+	Benchmark       string          // 倍数对应的基准利率代码，由 Config.RateProvider 提供
+	SamplePrincipal decimal.Decimal // 试算本金，为 0 时依次取产品的 MaxPrinciple、MinPrinciple
+	SamplePeriods   int             // 试算期数，为 0 时依次取产品的 MaxPeriods、MinPeriods
+	Reject          bool            // 超限时拒绝注册产品，否则只在报告中标记
+}
+
+// CostComponent 单项成本折算的年化贡献
+type CostComponent struct {
+	Name       string
+	AnnualRate decimal.Decimal
+}
+
+// ComplianceReport 产品综合成本合规检查结果
+type ComplianceReport struct {
+	ProductID  int64
+	Ceiling    decimal.Decimal // 本次检查使用的上限
+	AllInAPR   decimal.Decimal // 利息与服务费合计的名义年化成本（IRR 法）
+	Components []CostComponent // 计入 AllInAPR 的各成本项的年化贡献，按贡献从高到低排列
+	// 以下两项不计入 AllInAPR，单独列示
+	PenaltyRate     decimal.Decimal // 逾期罚息年利率
+	DefaultFeeRatio decimal.Decimal // 提前结清违约金占剩余本金的比例（一次性收取，非年化）
+	Exceeded        bool
+	Violations      []string // 超限项说明
+}
+
+// CheckCompliance 用试算本金和期数生成计划，计算产品的综合年化成本并与上限比较
+func CheckCompliance(p *Product, c CostCeiling) (*ComplianceReport, error) {
+	ceiling, err := c.ceiling()
+	if err != nil {
+		return nil, err
+	}
+	report := &ComplianceReport{ProductID: p.ID, Ceiling: ceiling}
+
+	principal := firstPositive(c.SamplePrincipal, p.MaxPrinciple, p.MinPrinciple, decimal.NewFromInt(10000))
+	periods := c.SamplePeriods
+	if periods <= 0 {
+		periods = p.MaxPeriods
+	}
+	if periods <= 0 {
+		periods = p.MinPeriods
+	}
+	if periods <= 0 {
+		periods = 12
+	}
+
+	product := p
+	if f := p.FloatingRate; f != nil {
+		rate, err := f.RateAt(cfg.Clock.Now())
+		if err != nil {
+			return nil, err
+		}
+		product = p.withRate(f.Clamp(decimal.Zero, rate))
+	}
+	sampleID := func() int64 { return 0 }
+	var schedules []Schedule
+	if product.RepayType == RepayTypeCustom {
+		schedules, err = sampleCustomSchedule(principal, int64(periods), product, sampleID)
+	} else {
+		schedules, err = buildSchedules(0, principal, int64(periods), product, sampleID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &LoanExtra{Loan: Loan{Principal: principal, TotalPeriods: periods, Product: product}}
+
+	// 依次计算只含利息、利息加单项费用、利息加全部费用三种口径的 APR，差额即为各项费用的贡献
+	base, err := aprWithFees(l, schedules, func(int) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	report.Components = append(report.Components, CostComponent{Name: "interest", AnnualRate: base})
+	for j, fee := range product.Fees {
+		apr, err := aprWithFees(l, schedules, func(k int) bool { return k == j })
+		if err != nil {
+			return nil, err
+		}
+		report.Components = append(report.Components, CostComponent{Name: "fee:" + fee.Name, AnnualRate: apr.Sub(base)})
+	}
+	report.AllInAPR, err = aprWithFees(l, schedules, func(int) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	report.PenaltyRate = p.Penalty
+	report.DefaultFeeRatio = p.DefaultRate
+	sort.SliceStable(report.Components, func(i, j int) bool {
+		return report.Components[i].AnnualRate.GreaterThan(report.Components[j].AnnualRate)
+	})
+
+	if report.AllInAPR.GreaterThan(ceiling) {
+		report.Violations = append(report.Violations, fmt.Sprintf("all-in APR %s exceeds ceiling %s", report.AllInAPR.StringFixed(4), ceiling.StringFixed(4)))
+	}
+	if p.Penalty.GreaterThan(ceiling) {
+		report.Violations = append(report.Violations, fmt.Sprintf("penalty rate %s exceeds ceiling %s", p.Penalty.StringFixed(4), ceiling.StringFixed(4)))
+	}
+	report.Exceeded = len(report.Violations) > 0
+	return report, nil
+}
+
+// sampleCustomSchedule 自定义计划产品没有公式可循，按产品的还款周期和日期规则生成等额本金的试算计划
+func sampleCustomSchedule(principal decimal.Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	start := cfg.Clock.Now()
+	dates, _, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
+	}
+	plan := make([]PlanItem, 0, periods)
+	p := principal.Div(decimal.NewFromInt(periods)).Round(2)
+	for i, d := range dates {
+		if int64(i) == periods-1 {
+			p = principal.Sub(p.Mul(decimal.NewFromInt(periods - 1)))
+		}
+		plan = append(plan, PlanItem{DueDate: d.Due, Principal: p})
+	}
+	return CustomSchedule(0, principal, start, plan, product, idGenerator)
+}
+
+func (c CostCeiling) ceiling() (decimal.Decimal, error) {
+	ceiling := c.Rate
+	if c.LPRMultiple.IsPositive() {
+		if cfg.RateProvider == nil {
+			return decimal.Zero, ErrNoRateProvider
+		}
+		lpr, err := cfg.RateProvider.Rate(c.Benchmark, cfg.Clock.Now())
+		if err != nil {
+			return decimal.Zero, err
+		}
+		m := lpr.Mul(c.LPRMultiple)
+		if !ceiling.IsPositive() || m.LessThan(ceiling) {
+			ceiling = m
+		}
+	}
+	if !ceiling.IsPositive() {
+		return decimal.Zero, ErrInvalidCeiling
+	}
+	return ceiling, nil
+}
+
+// aprWithFees 只保留 keep 选中的费用（按产品费用模板下标）计算名义 APR
+func aprWithFees(l *LoanExtra, schedules []Schedule, keep func(int) bool) (decimal.Decimal, error) {
+	sl := make([]Schedule, len(schedules))
+	for i, s := range schedules {
+		fees := make([]Fee, 0, len(s.ServiceFee))
+		for k, f := range s.ServiceFee {
+			if keep(k) {
+				fees = append(fees, f)
+			}
+		}
+		s.ServiceFee = fees
+		sl[i] = s
+	}
+	l.SetSchedules(sl)
+	apr, err := ScheduleAPR(l)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return apr.Nominal, nil
+}
+
+func firstPositive(ds ...decimal.Decimal) decimal.Decimal {
+	for _, d := range ds {
+		if d.IsPositive() {
+			return d
+		}
+	}
+	return decimal.Zero
+}
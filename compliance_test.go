@@ -0,0 +1,70 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestRegisterProductCostCeiling(t *testing.T) {
+	var id int64
+	c := Config{
+		IDGenerator: func() int64 { id++; return id },
+		Holiday:     HolidayFunc(func(time.Time) bool { return false }),
+		Clock:       fixedClock(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)),
+		CostCeiling: &CostCeiling{Rate: decimal.NewFromFloat(0.24), Reject: true},
+	}
+	tests := []struct {
+		name      string
+		repayType RepayType
+		rate      float64
+		exceeded  bool
+	}{
+		{"custom", RepayTypeCustom, 0.12, false},
+		{"custom over ceiling", RepayTypeCustom, 0.30, true},
+		{"annuity", RepayTypeEqualInstallment, 0.12, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewEngine(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Product{
+				ID:           1,
+				Interest:     decimal.NewFromFloat(tt.rate),
+				RepayType:    tt.repayType,
+				PeriodType:   PeriodMonth,
+				DayCountConv: BONDBASIS,
+				Penalty:      decimal.NewFromFloat(0.18),
+				DefaultRate:  decimal.NewFromFloat(0.02),
+			}
+			report, err := e.RegisterProduct(p)
+			if report == nil {
+				t.Fatalf("no compliance report, err = %v", err)
+			}
+			for _, c := range report.Components {
+				if c.Name == "penalty" || c.Name == "default_rate" {
+					t.Errorf("component %q is not part of the all-in APR", c.Name)
+				}
+			}
+			if report.Exceeded != tt.exceeded {
+				t.Errorf("Exceeded = %v, want %v (all-in APR %s)", report.Exceeded, tt.exceeded, report.AllInAPR)
+			}
+			if tt.exceeded && err != ErrCostCeilingExceeded {
+				t.Errorf("err = %v, want %v", err, ErrCostCeilingExceeded)
+			}
+			if !tt.exceeded && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+			if !report.PenaltyRate.Equal(p.Penalty) || !report.DefaultFeeRatio.Equal(p.DefaultRate) {
+				t.Errorf("PenaltyRate/DefaultFeeRatio = %s/%s, want %s/%s", report.PenaltyRate, report.DefaultFeeRatio, p.Penalty, p.DefaultRate)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package loancalc
 
 import (
-	"encoding/json"
 	"io"
 	"net/http"
 	"time"
@@ -21,17 +20,16 @@ type HolidayProvider interface {
 	IsHoliday(t time.Time) bool
 }
 
-// ChinaHolidayProvider 默认中国节假日+周末
+// ChinaHolidayProvider 默认中国节假日+周末，调休上班的周末按工作日处理
 type ChinaHolidayProvider struct{}
 
 func (ChinaHolidayProvider) IsHoliday(t time.Time) bool {
-	// 复用内部实现，并在 Start 时预热 convention.Holiday
-	d, ok := Holiday[t.Format("2006-01-02")]
-	if ok && d {
-		return true
-	}
-	wd := t.Weekday()
-	return wd == time.Saturday || wd == time.Sunday
+	return !ChinaHolidayProvider{}.DayKind(t).IsBusinessDay()
+}
+
+// DayKind 复用内部实现，并在 Start 时预热 convention.Holiday
+func (ChinaHolidayProvider) DayKind(t time.Time) DayKind {
+	return dayKindOf(Holiday, t)
 }
 
 // Config 运行时配置
@@ -39,8 +37,10 @@ type Config struct {
 	IDGenerator   IDGenerator
 	RoundStrategy RoundStrategy
 	//TODO 舍入策略适配
-	Holiday HolidayProvider
-	Clock   Clock
+	Holiday      HolidayProvider
+	Clock        Clock
+	RateProvider RateProvider // 浮动利率产品的基准利率来源
+	CostCeiling  *CostCeiling // 注册产品时的综合成本上限检查，为空时不检查
 }
 
 var Holiday = map[string]bool{}
@@ -57,8 +57,9 @@ func Start(c Config) error {
 	if c.Holiday == nil {
 		c.Holiday = ChinaHolidayProvider{}
 	}
-	if len(Holiday) == 0 {
-		// 尝试拉取当年中国法定节假日，失败不视为致命错误
+	if _, ok := c.Holiday.(ChinaHolidayProvider); ok && len(Holiday) == 0 {
+		// 仅默认的中国节假日实现需要联网拉取当年法定节假日，失败不视为致命错误；
+		// 无法访问外网时可改用 FileHolidayProvider 或预先填充 Holiday
 		if h, err := FetchCN(); err == nil {
 			Holiday = h
 		}
@@ -79,18 +80,6 @@ func FetchChinaHolidays() (map[string]bool, error) {
 
 // FetchCN 自动获取当年节假日+调休，返回 map[yyyy-mm-dd]bool，true 表示放假
 func FetchCN() (map[string]bool, error) {
-	type timorResp struct {
-		Code    int
-		Holiday map[string]struct {
-			Date    string
-			Holiday bool // true 放假 false 调休上班
-			Name    string
-			Wage    int    // 1 三倍工资
-			After   bool   // true 节后补班
-			Target  string // 对应节日
-			Rest    string
-		}
-	}
 	url := "https://timor.tech/api/holiday/year/"
 	resp, err := http.Get(url)
 	if err != nil {
@@ -103,14 +92,5 @@ func FetchCN() (map[string]bool, error) {
 		}
 	}(resp.Body)
 	body, _ := io.ReadAll(resp.Body)
-
-	var tr timorResp
-	if err = json.Unmarshal(body, &tr); err != nil {
-		return nil, err
-	}
-	m := make(map[string]bool)
-	for _, v := range tr.Holiday {
-		m[v.Date] = v.Holiday // 只记录放假日期
-	}
-	return m, nil
+	return parseTimor(body)
 }
@@ -8,23 +8,28 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-func AnnualToPeriodRate(annual decimal.Decimal, pt PeriodType, conv DayCountConv) (decimal.Decimal, error) {
+// AnnualToPeriodRate 把年利率折算为期利率，length 为自定义期别（N_DAYS/N_MONTHS）的长度，其他期别忽略
+func AnnualToPeriodRate(annual decimal.Decimal, pt PeriodType, length int, conv DayCountConv) (decimal.Decimal, error) {
+	return annualToPeriodRate(annual, pt, length, conv, cfg.Holiday)
+}
+
+// annualToPeriodRate length 为自定义期别（N_DAYS/N_MONTHS）的长度，holidays 用于 BUS/252 判断工作日
+func annualToPeriodRate(annual decimal.Decimal, pt PeriodType, length int, conv DayCountConv, holidays HolidayProvider) (decimal.Decimal, error) {
 	// 使用可注入的 Clock 以保证可测性
 	t := cfg.Clock.Now()
-	var next time.Time
-	switch pt {
-	case PeriodDay:
-		next = t.AddDate(0, 0, 1)
-	case PeriodMonth:
-		next = t.AddDate(0, 1, 0)
-	case PeriodYear:
-		next = t.AddDate(1, 0, 0)
-	case PeriodBiWeek:
-		next = t.AddDate(0, 0, 14)
-	default:
-		return decimal.Zero, errors.New("unknown period type")
+	next, err := nthPeriodDate(t, 1, pt, DateRule{PeriodLength: length})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if conv == ICMA {
+		// ICMA 下常规期的年化分数恰为 1/频率
+		n, err := periodsPerYear(pt, length)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return annual.Div(n), nil
 	}
-	ratio, err := EffectiveInterestRate(t, next, conv)
+	ratio, err := yearFraction(t, next, conv, holidays, pt, length)
 	if err != nil {
 		return decimal.Zero, err
 	}
@@ -85,7 +90,7 @@ func DaysAct365(start, end time.Time) (int, int) {
 // -------------------- Actual/Actual (ISDA) --------------------
 
 // DaysActActISDA returns actual days / yearDays(where the day falls)
-// If the period spans multiple years, caller should split and sum.
+// If the period spans multiple years, use YearFractionActActISDA which splits and sums.
 func DaysActActISDA(start, end time.Time) (int, int) {
 	days := int(end.Sub(start).Hours() / 24)
 	// denominator = year days of the year where 'start' lies
@@ -93,6 +98,38 @@ func DaysActActISDA(start, end time.Time) (int, int) {
 	return days, yearBase
 }
 
+// YearFractionActActISDA splits the period at each January 1 and sums
+// days-in-segment / days-in-that-year, as required by Act/Act ISDA.
+func YearFractionActActISDA(start, end time.Time) decimal.Decimal {
+	sign := decimal.NewFromInt(1)
+	if end.Before(start) {
+		start, end = end, start
+		sign = sign.Neg()
+	}
+	frac := decimal.Zero
+	for CompareDate(start, end) < 0 {
+		next := time.Date(start.Year()+1, time.January, 1, 0, 0, 0, 0, start.Location())
+		if CompareDate(next, end) > 0 {
+			next = end
+		}
+		days := decimal.NewFromInt(int64(daysBetween(start, next)))
+		frac = frac.Add(days.Div(decimal.NewFromInt(int64(YearDays(start)))))
+		start = next
+	}
+	return frac.Mul(sign)
+}
+
+// daysBetween returns calendar days between the dates of start and end, ignoring clock time and DST
+func daysBetween(start, end time.Time) int {
+	return int(dateOnly(end).Sub(dateOnly(start)).Hours() / 24)
+}
+
+// dateOnly 去掉时分秒，只保留日历日期（按 UTC 零点表示），避免按小时折算天数时丢失不足 24 小时的跨日
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
 // -------------------- Actual/365 (AFB, 365.25) --------------------
 
 // DaysAct365AFB returns actual days / 365.25
@@ -101,7 +138,117 @@ func DaysAct365AFB(start, end time.Time) (int, int) {
 	return days, 36525 // 调用方用 36525/100
 }
 
+// -------------------- 30E+/360 --------------------
+
+// Days360EPlus returns (numerator, denominator=360) under 30E+/360:
+//   - d1==31 → 30
+//   - d2==31 → first day of next month
+func Days360EPlus(start, end time.Time) (int, int) {
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 {
+		d2 = 1
+		m2++
+	}
+	days := (y2-y1)*360 + int(m2-m1)*30 + (d2 - d1)
+	return days, 360
+}
+
+// -------------------- NL/365 (No Leap) --------------------
+
+// DaysNL365 returns actual days excluding every Feb 29 in (start, end] / 365
+func DaysNL365(start, end time.Time) (int, int) {
+	return daysBetween(start, end) - leapDays(start, end), 365
+}
+
+// -------------------- Actual/365L --------------------
+
+// DaysAct365L returns actual days / 366 or / 365. For annual payments the
+// denominator is 366 if (start, end] contains Feb 29; otherwise it is 366 if
+// end falls in a leap year.
+func DaysAct365L(start, end time.Time, annual bool) (int, int) {
+	if annual {
+		if leapDays(start, end) > 0 {
+			return daysBetween(start, end), 366
+		}
+		return daysBetween(start, end), 365
+	}
+	return daysBetween(start, end), YearDays(end)
+}
+
+// -------------------- Actual/Actual (ICMA) --------------------
+
+// YearFractionICMA returns days(start, end) / (freq * days(refStart, refEnd)),
+// where [refStart, refEnd) is the regular coupon period containing the accrual period.
+func YearFractionICMA(start, end, refStart, refEnd time.Time, freq int) decimal.Decimal {
+	ref := daysBetween(refStart, refEnd)
+	if ref == 0 || freq <= 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromInt(int64(daysBetween(start, end))).Div(decimal.NewFromInt(int64(freq * ref)))
+}
+
+// yearFractionICMA 按付息周期 pt 计算 ICMA 年化分数：从 end 起逐个向前划出常规参考期，
+// 每个参考期内的实际天数除以（频率 × 参考期天数）后累加，不规则的短首期、长首期都按所在参考期折算
+func yearFractionICMA(start, end time.Time, pt PeriodType, length int) (decimal.Decimal, error) {
+	months, days, err := periodStep(pt, length)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	freq, err := periodsPerYear(pt, length)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	total := decimal.Zero
+	for refEnd := end; CompareDate(start, refEnd) < 0; {
+		refStart := refEnd.AddDate(0, 0, -days)
+		if months > 0 {
+			refStart = addMonths(refEnd, -months)
+		}
+		from := refStart
+		if CompareDate(start, refStart) > 0 {
+			from = start
+		}
+		ref := decimal.NewFromInt(int64(daysBetween(refStart, refEnd)))
+		total = total.Add(decimal.NewFromInt(int64(daysBetween(from, refEnd))).Div(freq.Mul(ref)))
+		refEnd = refStart
+	}
+	return total, nil
+}
+
+// -------------------- BUS/252 --------------------
+
+// DaysBus252 returns business days in [start, end) / 252
+func DaysBus252(start, end time.Time, isHoliday HolidayFunc) (int, int) {
+	return NewBusinessCalendar(isHoliday).BusinessDaysBetween(start, end), 252
+}
+
+// leapDays counts Feb 29s in (start, end]
+func leapDays(start, end time.Time) int {
+	n := 0
+	for y := start.Year(); y <= end.Year(); y++ {
+		if YearDays(time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)) != 366 {
+			continue
+		}
+		feb29 := time.Date(y, time.February, 29, 0, 0, 0, 0, start.Location())
+		if CompareDate(feb29, start) > 0 && CompareDate(feb29, end) <= 0 {
+			n++
+		}
+	}
+	return n
+}
+
 func EffectiveInterestRate(start, end time.Time, conv DayCountConv) (decimal.Decimal, error) {
+	return yearFraction(start, end, conv, cfg.Holiday, "", 0)
+}
+
+// yearFraction hp 为 BUS/252 使用的营业日日历；pt/length 为付息周期，ICMA 据此确定参考期和频率，
+// ACT365L 据此选择闰年规则，为空时按一年一次付息处理
+func yearFraction(start, end time.Time, conv DayCountConv, hp HolidayProvider, pt PeriodType, length int) (decimal.Decimal, error) {
 	var d, y int
 	switch conv {
 	case BONDBASIS:
@@ -113,9 +260,28 @@ func EffectiveInterestRate(start, end time.Time, conv DayCountConv) (decimal.Dec
 	case FIXED:
 		d, y = DaysAct365(start, end)
 	case ISDA:
-		d, y = DaysActActISDA(start, end)
+		return YearFractionActActISDA(start, end), nil
 	case AFB:
 		d, y = DaysAct365AFB(start, end)
+	case ICMA:
+		if pt == "" {
+			// 没有付息频率时以起始日开始的一整年为参考期
+			return YearFractionICMA(start, end, start, start.AddDate(1, 0, 0), 1), nil
+		}
+		return yearFractionICMA(start, end, pt, length)
+	case ACT365L:
+		// 未指定周期或按年付息时按 2 月 29 日规则，其余频率看期末日所在年份
+		months, _, err := periodStep(pt, length)
+		if pt != "" && err != nil {
+			return decimal.Zero, err
+		}
+		d, y = DaysAct365L(start, end, pt == "" || months == 12)
+	case EUROBONDPLUS:
+		d, y = Days360EPlus(start, end)
+	case NL365:
+		d, y = DaysNL365(start, end)
+	case BUS252:
+		d, y = DaysBus252(start, end, hp.IsHoliday)
 	default:
 		return decimal.NewFromInt(0), errors.New("unsupported day count")
 	}
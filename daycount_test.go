@@ -0,0 +1,87 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestYearFractionICMAUsesPaymentFrequency(t *testing.T) {
+	startTest(t, Config{})
+	p := &Product{PeriodType: PeriodMonth, DayCountConv: ICMA}
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	got, err := p.yearFraction(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 短首期落在 12-31 ~ 01-31 这个月度参考期内：16 / (12 × 31)
+	want := decimal.NewFromInt(16).Div(decimal.NewFromInt(12 * 31))
+	if !got.Equal(want) {
+		t.Errorf("yearFraction = %s, want %s", got, want)
+	}
+}
+
+func TestAct365LFrequency(t *testing.T) {
+	startTest(t, Config{})
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		pt   PeriodType
+		want decimal.Decimal
+	}{
+		// 月付：期末日在闰年，按 366
+		{"monthly", PeriodMonth, decimal.NewFromInt(30).Div(decimal.NewFromInt(366))},
+		// 年付：区间不含 2 月 29 日，按 365
+		{"annual", PeriodYear, decimal.NewFromInt(30).Div(decimal.NewFromInt(365))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Product{PeriodType: tt.pt, DayCountConv: ACT365L}
+			got, err := p.yearFraction(start, end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("yearFraction = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomPeriodLengthAPIs(t *testing.T) {
+	startTest(t, Config{})
+	annual := decimal.NewFromFloat(0.12)
+	tests := []struct {
+		pt     PeriodType
+		length int
+		conv   DayCountConv
+		next   time.Time
+		n      decimal.Decimal
+		rate   decimal.Decimal
+	}{
+		{PeriodNDays, 10, FIXED, time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC), decimal.NewFromFloat(36.5), annual.Mul(decimal.NewFromInt(10)).Div(decimal.NewFromInt(365))},
+		{PeriodNMonths, 2, BONDBASIS, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), decimal.NewFromInt(6), annual.Div(decimal.NewFromInt(6))},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.pt), func(t *testing.T) {
+			next, err := NextPeriodDate(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), tt.pt, tt.length, Unadjusted)
+			if err != nil || !next.Equal(tt.next) {
+				t.Errorf("NextPeriodDate = %v, %v, want %v", next, err, tt.next)
+			}
+			n, err := PeriodsPerYear(tt.pt, tt.length)
+			if err != nil || !n.Equal(tt.n) {
+				t.Errorf("PeriodsPerYear = %s, %v, want %s", n, err, tt.n)
+			}
+			rate, err := AnnualToPeriodRate(annual, tt.pt, tt.length, tt.conv)
+			if err != nil || !rate.Round(10).Equal(tt.rate.Round(10)) {
+				t.Errorf("AnnualToPeriodRate = %s, %v, want %s", rate, err, tt.rate)
+			}
+			if _, err := AnnualizeRate(rate, tt.pt, tt.length); err != nil {
+				t.Errorf("AnnualizeRate: %v", err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package loancalc
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 type Plugin interface {
@@ -37,22 +38,37 @@ func NewEngine(c Config) (*Engine, error) {
 	return &Engine{handlers: make(map[int64]*handler)}, err
 }
 
-// RegisterProduct 绑定产品与插件链
-func (e *Engine) RegisterProduct(p *Product, plugins ...Plugin) {
+// RegisterProduct 绑定产品与插件链。配置了 Config.CostCeiling 时先做综合成本检查并返回报告，
+// 超限且要求拒绝时不注册产品
+func (e *Engine) RegisterProduct(p *Product, plugins ...Plugin) (*ComplianceReport, error) {
+	var report *ComplianceReport
+	if cfg.CostCeiling != nil {
+		r, err := CheckCompliance(p, *cfg.CostCeiling)
+		if err != nil {
+			return nil, err
+		}
+		if r.Exceeded && cfg.CostCeiling.Reject {
+			return r, ErrCostCeilingExceeded
+		}
+		report = r
+	}
 	h := &handler{product: p}
 	if len(plugins) > 0 {
 		h.plugins = append(h.plugins, plugins...)
 	}
 	// 默认核心处理流程：可根据产品类型定制
 	h.buildFunc = func(ctx *LoanContext) ([]Schedule, error) {
-		switch p.RepayType {
-		case RepayTypeEqualInstallment:
-			return AnnuitySchedule(ctx.Loan.ID, ctx.Loan.Principal, int64(ctx.Loan.TotalPeriods), p, cfg.IDGenerator)
-		case RepayTypeEqualPrincipal:
-			return EqualPrincipalSchedule(ctx.Loan.ID, ctx.Loan.Principal, int64(ctx.Loan.TotalPeriods), p, cfg.IDGenerator)
-		default:
-			return nil, ErrUnSupportRepayType
+		p := ctx.Loan.CurrentProduct()
+		schedules, err := buildSchedules(ctx.Loan.ID, ctx.Loan.Principal, int64(ctx.Loan.TotalPeriods), p, cfg.IDGenerator)
+		if err != nil || p.RepayType != RepayTypeFlatRate {
+			return schedules, err
+		}
+		apr, err := FlatRateAPR(ctx.Loan.Principal, schedules, p)
+		if err != nil {
+			return nil, err
 		}
+		ctx.Loan.APR = apr.Effective
+		return schedules, nil
 	}
 	h.repayFunc = func(ctx *LoanContext, info RepayInfo) (Decimal, error) {
 		if info.PrepayStrategy == PrepayNot {
@@ -61,6 +77,7 @@ func (e *Engine) RegisterProduct(p *Product, plugins ...Plugin) {
 		return PreRepay(ctx.Loan, info.Amount, cfg.IDGenerator, info.PrepayStrategy)
 	}
 	e.handlers[p.ID] = h
+	return report, nil
 }
 
 // BuildSchedules 根据产品还款方式生成计划
@@ -69,13 +86,35 @@ func (e *Engine) BuildSchedules(l Loan) (*LoanExtra, error) {
 	if !ok {
 		return nil, errors.New("product not registered")
 	}
+	return e.build(h, l, h.buildFunc)
+}
+
+// BuildCustomSchedules 按约定的还款计划生成计划，适用于协议还款、重组等无固定公式的贷款。
+// 产品还款方式必须是 CUSTOM，否则后续提前还款重算时会按公式重建计划而丢失约定
+func (e *Engine) BuildCustomSchedules(l Loan, plan []PlanItem) (*LoanExtra, error) {
+	h, ok := e.handlers[l.Product.ID]
+	if !ok {
+		return nil, errors.New("product not registered")
+	}
+	if l.Product.RepayType != RepayTypeCustom {
+		return nil, ErrUnSupportRepayType
+	}
+	return e.build(h, l, func(ctx *LoanContext) ([]Schedule, error) {
+		return CustomSchedule(ctx.Loan.ID, ctx.Loan.Principal, cfg.Clock.Now(), plan, ctx.Loan.CurrentProduct(), cfg.IDGenerator)
+	})
+}
+
+func (e *Engine) build(h *handler, l Loan, buildFunc func(ctx *LoanContext) ([]Schedule, error)) (*LoanExtra, error) {
 	ctx := &LoanContext{Context: context.Background(), Loan: l.ToLoanExtra(), Params: map[string]any{}}
 	for _, p := range h.plugins {
 		if err := p.BeforeCreate(ctx); err != nil {
 			return nil, err
 		}
 	}
-	schedules, err := h.buildFunc(ctx)
+	if err := initRate(ctx.Loan); err != nil {
+		return nil, err
+	}
+	schedules, err := buildFunc(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +151,22 @@ func (e *Engine) Repay(l *LoanExtra, info RepayInfo) (*LoanExtra, Decimal, error
 	return ctx.Loan, remaining, nil
 }
 
+// Reprice 浮动利率贷款的重定价入口，通常由每日跑批调用
+func (e *Engine) Reprice(l *LoanExtra, asOf time.Time) (bool, error) {
+	if _, ok := e.handlers[l.Product.ID]; !ok {
+		return false, errors.New("product not registered")
+	}
+	return Reprice(l, asOf, cfg.IDGenerator)
+}
+
+// RunOverdueBatch 逾期跑批入口，每日日终调用，同一天重复调用结果不变
+func (e *Engine) RunOverdueBatch(l *LoanExtra, asOf time.Time) error {
+	if _, ok := e.handlers[l.Product.ID]; !ok {
+		return errors.New("product not registered")
+	}
+	return RunOverdueBatch(l, asOf, cfg.IDGenerator)
+}
+
 // SetHandlerFuncs 允许为指定产品自定义核心流程
 func (e *Engine) SetHandlerFuncs(productID int64,
 	build func(ctx *LoanContext) ([]Schedule, error),
@@ -10,4 +10,11 @@ var (
 	ErrInsufficientForPenalty  = errors.New("insufficient amount to cover penalty interest")
 	ErrInsufficientForSchedule = errors.New("insufficient amount to cover schedule")
 	ErrUnSupportRepayType      = errors.New("unsupported repay type")
+	ErrInvalidPlan             = errors.New("invalid repayment plan")
+	ErrNoRateProvider          = errors.New("rate provider not configured")
+	ErrNoCashFlow              = errors.New("not enough cash flows")
+	ErrInvalidCeiling          = errors.New("invalid cost ceiling")
+	ErrCostCeilingExceeded     = errors.New("product cost exceeds ceiling")
+	ErrIRRNotConverge          = errors.New("irr does not converge")
+	ErrNegativeAmortization    = errors.New("interest allocation produces negative principal")
 )
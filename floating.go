@@ -0,0 +1,292 @@
+package loancalc
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RateProvider 提供基准利率（如 LPR）的历史值与当前值
+type RateProvider interface {
+	// Rate 返回 at 当日生效的基准利率（年化）
+	Rate(benchmark string, at time.Time) (decimal.Decimal, error)
+}
+
+// FloatingRate 浮动利率定义：执行利率 = 基准利率 + 加点，按重定价规则定期调整
+type FloatingRate struct {
+	Benchmark     string          `db:"benchmark" json:"benchmark"`                     // 基准利率代码，如 LPR1Y、LPR5Y
+	Spread        decimal.Decimal `db:"spread" json:"spread"`                           // 加点（可为负）
+	RepriceRule   RepriceRule     `db:"reprice_rule" json:"reprice_rule"`               // 重定价日规则
+	RepriceMonths int             `db:"reprice_months" json:"reprice_months,omitempty"` // 重定价周期（月），为 0 时按 12 个月
+	Cap           decimal.Decimal `db:"cap" json:"cap"`                                 // 存续期利率上限，为 0 时不限
+	Floor         decimal.Decimal `db:"floor" json:"floor"`                             // 存续期利率下限，为 0 时不限
+	MaxChange     decimal.Decimal `db:"max_change" json:"max_change"`                   // 单次重定价的最大调整幅度，为 0 时不限
+}
+
+// Clamp 按单次调整幅度和存续期上下限约束新的执行利率，prev 为调整前的执行利率（首次定价时传 0）
+func (f *FloatingRate) Clamp(prev, next decimal.Decimal) decimal.Decimal {
+	if f.MaxChange.IsPositive() && !prev.IsZero() {
+		next = decimal.Min(next, prev.Add(f.MaxChange))
+		next = decimal.Max(next, prev.Sub(f.MaxChange))
+	}
+	if f.Cap.IsPositive() {
+		next = decimal.Min(next, f.Cap)
+	}
+	if f.Floor.IsPositive() {
+		next = decimal.Max(next, f.Floor)
+	}
+	return next
+}
+
+// RateAt 返回 at 当日的执行利率
+func (f *FloatingRate) RateAt(at time.Time) (decimal.Decimal, error) {
+	if cfg.RateProvider == nil {
+		return decimal.Zero, ErrNoRateProvider
+	}
+	b, err := cfg.RateProvider.Rate(f.Benchmark, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return b.Add(f.Spread), nil
+}
+
+// NextReset 返回 after 之后的第一个重定价日，anchor 为放款日
+func (f *FloatingRate) NextReset(anchor, after time.Time) time.Time {
+	months := f.RepriceMonths
+	if months <= 0 {
+		months = 12
+	}
+	base := anchor
+	if f.RepriceRule == RepriceJanFirst {
+		base = time.Date(anchor.Year(), time.January, 1, 0, 0, 0, 0, anchor.Location())
+	}
+	for k := 1; ; k++ {
+		d := addMonths(base, k*months)
+		if CompareDate(d, after) > 0 {
+			return d
+		}
+	}
+}
+
+// CurrentProduct 返回按贷款当前执行利率计息的产品视图，固定利率产品直接返回 Product
+func (l *LoanExtra) CurrentProduct() *Product {
+	if l.Product.FloatingRate == nil || l.Rate.IsZero() {
+		return l.Product
+	}
+	return l.Product.withRate(l.Rate)
+}
+
+// withRate 复制一份产品并替换执行利率
+func (s *Product) withRate(rate decimal.Decimal) *Product {
+	p := *s
+	p.Interest = rate
+	return &p
+}
+
+// initRate 在生成计划前确定贷款的初始执行利率
+func initRate(l *LoanExtra) error {
+	now := cfg.Clock.Now()
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = now
+	}
+	if l.Product.FloatingRate == nil {
+		l.Rate = l.Product.Interest
+		return nil
+	}
+	f := l.Product.FloatingRate
+	rate, err := f.RateAt(now)
+	if err != nil {
+		return err
+	}
+	l.Rate = f.Clamp(decimal.Zero, rate)
+	l.RepricedAt = now
+	return nil
+}
+
+// Reprice 检查截至 asOf 是否经过了重定价日，若经过则按新利率（受上下限和单次调整幅度约束）
+// 重新生成未还各期，已还各期保持不变。
+// 返回执行利率是否发生了变化
+func Reprice(l *LoanExtra, asOf time.Time, gen IDGenerator) (bool, error) {
+	f := l.Product.FloatingRate
+	if f == nil {
+		return false, nil
+	}
+	last := l.RepricedAt
+	if last.IsZero() {
+		last = l.CreatedAt
+	}
+	reset := time.Time{}
+	for d := f.NextReset(l.CreatedAt, last); CompareDate(d, asOf) <= 0; d = f.NextReset(l.CreatedAt, d) {
+		reset = d
+	}
+	if reset.IsZero() {
+		return false, nil
+	}
+	rate, err := f.RateAt(reset)
+	if err != nil {
+		return false, err
+	}
+	rate = f.Clamp(l.Rate, rate)
+	l.RepricedAt = reset
+	if rate.Equal(l.Rate) {
+		return false, nil
+	}
+	prev := l.Rate
+	l.Rate = rate
+	return true, regenerateUnpaid(l, reset, prev, gen)
+}
+
+// regenerateUnpaid 按当前执行利率重算计息区间在重定价日 reset 之后结束的未还各期，沿用原有期次、还款日和计息区间，
+// 各期按原计息区间重新计息，跨越 reset 的一期在 reset 之前仍按调整前利率 prevRate 计息；
+// 计息区间在 reset 之前已结束的各期（已到期未还）保持原样，不追溯调整。
+// 等额本息按剩余期限重算供款（已用的宽限期不再重复，保留气球贷尾款和阶梯形状），其他还款方式各期本金不变
+func regenerateUnpaid(l *LoanExtra, reset time.Time, prevRate decimal.Decimal, gen IDGenerator) error {
+	var unpaid []int
+	balance := decimal.Zero
+	for i, s := range l.Schedules {
+		if s.Status == ScheduleUnpaid && CompareDate(s.AccrualEnd, reset) > 0 {
+			unpaid = append(unpaid, i)
+			balance = balance.Add(s.Principal)
+		}
+	}
+	if len(unpaid) == 0 {
+		return nil
+	}
+	product := l.CurrentProduct()
+	r, err := product.periodRate()
+	if err != nil {
+		return err
+	}
+	prevR, err := l.Product.withRate(prevRate).periodRate()
+	if err != nil {
+		return err
+	}
+	var payments []decimal.Decimal
+	if product.RepayType == RepayTypeEqualInstallment {
+		payments = remainingPayments(l, unpaid, balance, r)
+	}
+
+	newSchedules := make([]Schedule, 0, len(unpaid))
+	for k, i := range unpaid {
+		old := l.Schedules[i]
+		stub := old.Period == 1 && hasStub(old.AccrualStart, product)
+		base := balance
+		if product.RepayType == RepayTypeFlatRate {
+			// 等本等息始终按合同本金计息
+			base = l.Principal
+		}
+		interest, err := repricedInterest(base, old, reset, prevRate, prevR, r, product, stub)
+		if err != nil {
+			return err
+		}
+		p := old.Principal
+		if payments != nil {
+			p = decimal.Zero
+			if old.Period > product.GraceTerm {
+				p = payments[k].Sub(interest)
+				if stub {
+					p = payments[k].Sub(balance.Mul(r))
+				}
+			}
+		}
+		if k == len(unpaid)-1 {
+			p = balance
+		}
+		balance = balance.Sub(p)
+		id := gen()
+		s := NewSchedule(id, l.ID, old.Period, old.DueDate, p, interest, scheduleFees(product, id, gen))
+		s.AccrualStart, s.AccrualEnd = old.AccrualStart, old.AccrualEnd
+		// 已逾期或部分还款的期次沿用原有状态
+		s.Overdue, s.TotalPaymentPaid = old.Overdue, old.TotalPaymentPaid
+		newSchedules = append(newSchedules, *s)
+	}
+	newSchedules, err = allocateInterest(product, newSchedules)
+	if err != nil {
+		return err
+	}
+	for k, i := range unpaid {
+		l.reassignOverdue(&l.Schedules[i], &newSchedules[k])
+		l.Schedules[i].Status = ScheduleRemoved
+	}
+	for _, ns := range newSchedules {
+		l.AddSchedule(ns)
+	}
+	return nil
+}
+
+// remainingPayments 按剩余期限重算等额本息未还各期的供款，下标与 unpaid 对应，宽限期内的期次为 0。
+// 气球贷按剩余的名义摊还期数或原定尾款计算，阶梯供款从已摊还的期数之后接续
+func remainingPayments(l *LoanExtra, unpaid []int, balance, r decimal.Decimal) []decimal.Decimal {
+	product := l.CurrentProduct()
+	var m, elapsed int64
+	for _, i := range unpaid {
+		period := l.Schedules[i].Period
+		if period <= product.GraceTerm {
+			continue
+		}
+		if m == 0 {
+			elapsed = int64(period - 1 - product.GraceTerm)
+		}
+		m++
+	}
+	payments := make([]decimal.Decimal, len(unpaid))
+	if m == 0 {
+		return payments
+	}
+	residual := l.Principal.Mul(product.BalloonRatio)
+	pwt := AnnuityPayment(balance, m, r)
+	switch {
+	case int64(product.BalloonTerm)-elapsed > m:
+		pwt = AnnuityPayment(balance, int64(product.BalloonTerm)-elapsed, r)
+	case product.BalloonRatio.IsPositive():
+		pwt = BalloonPayment(balance, m, r, residual)
+	}
+	steps := graduatedPayments(balance, m, elapsed, r, residual, product)
+	a := 0
+	for k, i := range unpaid {
+		if l.Schedules[i].Period <= product.GraceTerm {
+			continue
+		}
+		payments[k] = pwt
+		if steps != nil {
+			payments[k] = steps[a]
+		}
+		a++
+	}
+	return payments
+}
+
+// repricedInterest 按原计息区间重新计算一期利息，区间在 reset 之前的部分按调整前利率计息、之后的部分按新利率计息：
+// 按天计息的按实际区间拆分，按期计息的按 reset 前后所占比例拆分期利率
+func repricedInterest(base decimal.Decimal, s Schedule, reset time.Time, prevRate, prevR, r decimal.Decimal, product *Product, stub bool) (decimal.Decimal, error) {
+	byDays := product.ActualPeriodInterest || stub || product.RepayType == RepayTypeBullet || product.RepayType == RepayTypeCustom
+	before, after := decimal.Zero, decimal.Zero
+	var err error
+	if CompareDate(s.AccrualStart, reset) < 0 {
+		end := s.AccrualEnd
+		if CompareDate(reset, end) < 0 {
+			end = reset
+		}
+		if before, err = product.yearFraction(s.AccrualStart, end); err != nil {
+			return decimal.Zero, err
+		}
+	}
+	if CompareDate(reset, s.AccrualEnd) < 0 {
+		start := s.AccrualStart
+		if CompareDate(start, reset) < 0 {
+			start = reset
+		}
+		if after, err = product.yearFraction(start, s.AccrualEnd); err != nil {
+			return decimal.Zero, err
+		}
+	}
+	if byDays {
+		return base.Mul(prevRate.Mul(before).Add(product.Interest.Mul(after))), nil
+	}
+	whole := before.Add(after)
+	if whole.IsZero() {
+		return base.Mul(r), nil
+	}
+	w := before.Div(whole)
+	return base.Mul(prevR.Mul(w).Add(r.Mul(one.Sub(w)))), nil
+}
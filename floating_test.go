@@ -0,0 +1,94 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// stepRates 在 switchAt 之前返回 before，之后返回 after
+type stepRates struct {
+	switchAt      time.Time
+	before, after decimal.Decimal
+}
+
+func (p stepRates) Rate(_ string, at time.Time) (decimal.Decimal, error) {
+	if at.Before(p.switchAt) {
+		return p.before, nil
+	}
+	return p.after, nil
+}
+
+func TestRepriceKeepsPastDuePeriods(t *testing.T) {
+	reset := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	gen := startTest(t, Config{RateProvider: stepRates{
+		switchAt: reset,
+		before:   decimal.NewFromFloat(0.045),
+		after:    decimal.NewFromFloat(0.055),
+	}})
+	p := &Product{
+		ID:           1,
+		RepayType:    RepayTypeInterestOnly,
+		PeriodType:   PeriodMonth,
+		DayCountConv: BONDBASIS,
+		FloatingRate: &FloatingRate{Benchmark: "LPR1Y", RepriceRule: RepriceJanFirst},
+	}
+	e := &Engine{handlers: map[int64]*handler{}}
+	if _, err := e.RegisterProduct(p); err != nil {
+		t.Fatal(err)
+	}
+	l, err := e.BuildSchedules(Loan{ID: 1, Principal: decimal.NewFromInt(4000), TotalPeriods: 12, Product: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 前 10 期已还，第 11 期（2025-11-15 ~ 2025-12-15）在重定价日前已到期未还，第 12 期跨越重定价日
+	for i := 0; i < 10; i++ {
+		l.Schedules[i].Status = SchedulePaid
+	}
+	// 第 12 期在跑批时已逾期且部分还款，重算后应保留
+	l.Schedules[11].Overdue = true
+	l.Schedules[11].TotalPaymentPaid = decimal.NewFromInt(5)
+	changed, err := Reprice(l, reset.AddDate(0, 1, 0), gen)
+	if err != nil || !changed {
+		t.Fatalf("Reprice = %v, %v", changed, err)
+	}
+
+	interest := map[int]string{}
+	for _, s := range l.Schedules {
+		if s.Status == ScheduleRemoved {
+			continue
+		}
+		interest[s.Period] = s.Interest.StringFixed(2)
+		if s.Period == 12 && (!s.Overdue || !s.TotalPaymentPaid.Equal(decimal.NewFromInt(5))) {
+			t.Errorf("period 12 lost overdue state: overdue=%v paid=%s", s.Overdue, s.TotalPaymentPaid)
+		}
+	}
+	// 第 11 期在重定价日前已结束，保持 4.5% 的利息；第 12 期 16 天按 4.5%、14 天按 5.5%
+	if interest[11] != "15.00" {
+		t.Errorf("period 11 interest = %s, want 15.00", interest[11])
+	}
+	if interest[12] != "16.56" {
+		t.Errorf("period 12 interest = %s, want 16.56", interest[12])
+	}
+}
+
+func TestRepricedInterestBeforeReset(t *testing.T) {
+	startTest(t, Config{})
+	p := &Product{Interest: decimal.NewFromFloat(0.055), PeriodType: PeriodMonth, DayCountConv: BONDBASIS}
+	s := Schedule{
+		AccrualStart: time.Date(2025, 11, 15, 0, 0, 0, 0, time.UTC),
+		AccrualEnd:   time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC),
+	}
+	reset := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prevRate := decimal.NewFromFloat(0.045)
+	prevR := prevRate.Div(decimal.NewFromInt(12))
+	r := p.Interest.Div(decimal.NewFromInt(12))
+	got, err := repricedInterest(decimal.NewFromInt(4000), s, reset, prevRate, prevR, r, p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.StringFixed(2) != "15.00" {
+		t.Errorf("interest = %s, want 15.00", got.StringFixed(2))
+	}
+}
@@ -0,0 +1,389 @@
+package loancalc
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HolidayFormat 节假日数据文件格式
+type HolidayFormat string
+
+const (
+	HolidayJSON HolidayFormat = "JSON" // {"2025-01-01": true, "2025-01-26": false} 或 timor.tech 接口返回格式
+	HolidayCSV  HolidayFormat = "CSV"  // 每行 日期,是否放假[,名称]，是否放假可写 true/false、1/0、休/班
+	HolidayICS  HolidayFormat = "ICS"  // iCalendar，SUMMARY 含“班”的事件视为调休上班，其余视为放假
+)
+
+// DayKind 日历上一天的类型
+type DayKind string
+
+const (
+	DayWorkday       DayKind = "WORKDAY"        // 普通工作日
+	DayWeekend       DayKind = "WEEKEND"        // 周末
+	DayHoliday       DayKind = "HOLIDAY"        // 法定节假日（含调休放假）
+	DayMakeUpWorkday DayKind = "MAKEUP_WORKDAY" // 调休上班日，虽是周末但需要上班
+)
+
+// IsBusinessDay 是否为营业日
+func (k DayKind) IsBusinessDay() bool {
+	return k == DayWorkday || k == DayMakeUpWorkday
+}
+
+// Calendar 区分工作日、周末、节假日与调休上班日的日历，IsHoliday 应与 !DayKind(t).IsBusinessDay() 一致
+type Calendar interface {
+	HolidayProvider
+	DayKind(t time.Time) DayKind
+}
+
+// dayKindOf 按 yyyy-mm-dd → 是否放假 的记录判断日期类型，没有记录时按周末判断
+func dayKindOf(days map[string]bool, t time.Time) DayKind {
+	if h, ok := days[t.Format("2006-01-02")]; ok {
+		if h {
+			return DayHoliday
+		}
+		return DayMakeUpWorkday
+	}
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return DayWeekend
+	}
+	return DayWorkday
+}
+
+// kindOf 取任意 HolidayProvider 的日期类型，未实现 Calendar 的 provider 只能区分周末与节假日。
+// 这类 provider 没有标记的周六日按普通工作日处理，是否休息交给 WithWeekend 等组合日历的周末定义决定，
+// 不能当作调休上班日，否则会覆盖新的周末定义
+func kindOf(hp HolidayProvider, t time.Time) DayKind {
+	if c, ok := hp.(Calendar); ok {
+		return c.DayKind(t)
+	}
+	weekend := t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+	switch {
+	case hp.IsHoliday(t) && weekend:
+		return DayWeekend
+	case hp.IsHoliday(t):
+		return DayHoliday
+	default:
+		return DayWorkday
+	}
+}
+
+// JointCalendar 联合日历：所有日历都营业的日子才是营业日（节假日取并集），如境内外两地同时开门
+func JointCalendar(cals ...HolidayProvider) Calendar {
+	return jointCalendar(cals)
+}
+
+type jointCalendar []HolidayProvider
+
+func (j jointCalendar) DayKind(t time.Time) DayKind {
+	kind := DayWorkday
+	for _, c := range j {
+		switch k := kindOf(c, t); k {
+		case DayHoliday:
+			return DayHoliday
+		case DayWeekend:
+			kind = DayWeekend
+		case DayMakeUpWorkday:
+			if kind == DayWorkday {
+				kind = DayMakeUpWorkday
+			}
+		}
+	}
+	return kind
+}
+
+func (j jointCalendar) IsHoliday(t time.Time) bool {
+	return !j.DayKind(t).IsBusinessDay()
+}
+
+// AnyCalendar 任一日历营业即为营业日（节假日取交集）
+func AnyCalendar(cals ...HolidayProvider) Calendar {
+	return anyCalendar(cals)
+}
+
+type anyCalendar []HolidayProvider
+
+func (a anyCalendar) DayKind(t time.Time) DayKind {
+	kind := DayWeekend
+	open := false
+	for _, c := range a {
+		switch k := kindOf(c, t); k {
+		case DayWorkday:
+			return DayWorkday
+		case DayMakeUpWorkday:
+			open = true
+		case DayHoliday:
+			kind = DayHoliday
+		}
+	}
+	if open {
+		return DayMakeUpWorkday
+	}
+	return kind
+}
+
+func (a anyCalendar) IsHoliday(t time.Time) bool {
+	return !a.DayKind(t).IsBusinessDay()
+}
+
+// WithWeekend 用新的周末定义覆盖日历原有的周末（如周五、周六休息的市场），节假日和调休上班日保持不变
+func WithWeekend(base HolidayProvider, weekend ...time.Weekday) Calendar {
+	mask := make(map[time.Weekday]bool, len(weekend))
+	for _, wd := range weekend {
+		mask[wd] = true
+	}
+	return weekendCalendar{base: base, weekend: mask}
+}
+
+type weekendCalendar struct {
+	base    HolidayProvider
+	weekend map[time.Weekday]bool
+}
+
+func (w weekendCalendar) DayKind(t time.Time) DayKind {
+	switch k := kindOf(w.base, t); k {
+	case DayHoliday:
+		return k
+	case DayMakeUpWorkday:
+		// 原日历的调休上班日只有落在新周末上时才仍是调休上班
+		if w.weekend[t.Weekday()] {
+			return k
+		}
+		return DayWorkday
+	default:
+		if w.weekend[t.Weekday()] {
+			return DayWeekend
+		}
+		return DayWorkday
+	}
+}
+
+func (w weekendCalendar) IsHoliday(t time.Time) bool {
+	return !w.DayKind(t).IsBusinessDay()
+}
+
+// FileHolidayProvider 从本地文件或内嵌数据加载节假日与调休上班日，不依赖网络，
+// 适合无法访问外网的生产环境得到确定的还款计划
+type FileHolidayProvider struct {
+	days map[string]bool // yyyy-mm-dd → true 放假，false 调休上班
+}
+
+// NewFileHolidayProvider 按扩展名（.json/.csv/.ics）识别格式并加载节假日文件
+func NewFileHolidayProvider(path string) (*FileHolidayProvider, error) {
+	var format HolidayFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = HolidayJSON
+	case ".csv":
+		format = HolidayCSV
+	case ".ics", ".ical":
+		format = HolidayICS
+	default:
+		return nil, fmt.Errorf("unknown holiday file format: %s", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadHolidays(f, format)
+}
+
+// LoadHolidays 从 r 读取节假日数据，可配合 go:embed 使用内嵌数据
+func LoadHolidays(r io.Reader, format HolidayFormat) (*FileHolidayProvider, error) {
+	var days map[string]bool
+	var err error
+	switch format {
+	case HolidayJSON:
+		days, err = parseHolidayJSON(r)
+	case HolidayCSV:
+		days, err = parseHolidayCSV(r)
+	case HolidayICS:
+		days, err = parseHolidayICS(r)
+	default:
+		return nil, fmt.Errorf("unknown holiday format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileHolidayProvider{days: days}, nil
+}
+
+// DayKind 文件中有记录的日期以文件为准（放假或调休上班），其余日期按周末判断
+func (p *FileHolidayProvider) DayKind(t time.Time) DayKind {
+	return dayKindOf(p.days, t)
+}
+
+func (p *FileHolidayProvider) IsHoliday(t time.Time) bool {
+	return !p.DayKind(t).IsBusinessDay()
+}
+
+// Days 返回加载的全部日期记录的副本，可用于预热 Holiday
+func (p *FileHolidayProvider) Days() map[string]bool {
+	m := make(map[string]bool, len(p.days))
+	for k, v := range p.days {
+		m[k] = v
+	}
+	return m
+}
+
+func parseHolidayJSON(r io.Reader) (map[string]bool, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, err
+	}
+	if _, ok := probe["holiday"]; ok {
+		return parseTimor(body)
+	}
+	m := make(map[string]bool, len(probe))
+	for k, v := range probe {
+		var h bool
+		if err := json.Unmarshal(v, &h); err != nil {
+			return nil, fmt.Errorf("holiday %s: %w", k, err)
+		}
+		d, err := time.Parse("2006-01-02", k)
+		if err != nil {
+			return nil, err
+		}
+		m[d.Format("2006-01-02")] = h
+	}
+	return m, nil
+}
+
+// parseTimor 解析 timor.tech 节假日接口的返回
+func parseTimor(body []byte) (map[string]bool, error) {
+	type timorResp struct {
+		Code    int
+		Holiday map[string]struct {
+			Date    string
+			Holiday bool // true 放假 false 调休上班
+			Name    string
+			Wage    int    // 1 三倍工资
+			After   bool   // true 节后补班
+			Target  string // 对应节日
+			Rest    string
+		}
+	}
+	var tr timorResp
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, err
+	}
+	m := make(map[string]bool)
+	for _, v := range tr.Holiday {
+		m[v.Date] = v.Holiday
+	}
+	return m, nil
+}
+
+func parseHolidayCSV(r io.Reader) (map[string]bool, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]bool, len(records))
+	for i, rec := range records {
+		if len(rec) == 0 || strings.HasPrefix(rec[0], "#") {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(rec[0]))
+		if err != nil {
+			if i == 0 {
+				continue // 表头
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		h := true
+		if len(rec) > 1 {
+			switch strings.ToLower(strings.TrimSpace(rec[1])) {
+			case "true", "1", "休", "holiday", "":
+				h = true
+			case "false", "0", "班", "workday":
+				h = false
+			default:
+				return nil, fmt.Errorf("line %d: invalid holiday flag %q", i+1, rec[1])
+			}
+		}
+		m[d.Format("2006-01-02")] = h
+	}
+	return m, nil
+}
+
+func parseHolidayICS(r io.Reader) (map[string]bool, error) {
+	// 先展开折行（以空格或制表符开头的行属于上一行）
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]bool)
+	var start, end time.Time
+	var summary string
+	inEvent := false
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if i := strings.IndexByte(name, ';'); i >= 0 {
+			name = name[:i]
+		}
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART":
+			start, _ = parseICSDate(value)
+		case "DTEND":
+			end, _ = parseICSDate(value)
+		case "SUMMARY":
+			summary = value
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q without DTSTART", summary)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1) // DTEND 为开区间
+			}
+			h := !strings.Contains(summary, "班") && !strings.Contains(strings.ToLower(summary), "workday")
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				m[d.Format("2006-01-02")] = h
+			}
+		}
+	}
+	return m, nil
+}
+
+func parseICSDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if len(v) >= 8 {
+		v = v[:8]
+	}
+	return time.Parse("20060102", v)
+}
@@ -0,0 +1,27 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithWeekendOverPlainProvider(t *testing.T) {
+	// 只列节假日、不标记周末的 provider
+	newYear := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := HolidayFunc(func(t time.Time) bool { return CompareDate(t, newYear) == 0 })
+	cal := WithWeekend(base, time.Friday, time.Saturday)
+	tests := []struct {
+		day  time.Time
+		want DayKind
+	}{
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), DayHoliday},
+		{time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), DayWeekend}, // 周五
+		{time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), DayWeekend}, // 周六
+		{time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), DayWorkday}, // 周日
+	}
+	for _, tt := range tests {
+		if got := cal.DayKind(tt.day); got != tt.want {
+			t.Errorf("DayKind(%s) = %s, want %s", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package loancalc
+
+import (
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	irrMaxIter   = 200
+	irrTolerance = 1e-12
+)
+
+// PeriodsPerYear 返回一年包含的期数，用于把期利率年化；length 为自定义期别（N_DAYS/N_MONTHS）的长度
+func PeriodsPerYear(pt PeriodType, length int) (decimal.Decimal, error) {
+	return periodsPerYear(pt, length)
+}
+
+func periodsPerYear(pt PeriodType, length int) (decimal.Decimal, error) {
+	months, days, err := periodStep(pt, length)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if months > 0 {
+		return decimal.NewFromInt(12).Div(decimal.NewFromInt(int64(months))), nil
+	}
+	return decimal.NewFromInt(365).Div(decimal.NewFromInt(int64(days))), nil
+}
+
+// APR 年化利率披露结果
+type APR struct {
+	PeriodRate decimal.Decimal // 每期内部收益率
+	Nominal    decimal.Decimal // 名义年化利率：期利率 × 每年期数
+	Effective  decimal.Decimal // 实际年化利率（按期复利）：(1+期利率)^每年期数 - 1
+}
+
+// AnnualizeRate 把期利率折算为名义和实际年化利率，length 同 PeriodsPerYear
+func AnnualizeRate(periodRate decimal.Decimal, pt PeriodType, length int) (APR, error) {
+	n, err := periodsPerYear(pt, length)
+	if err != nil {
+		return APR{}, err
+	}
+	return annualizeRate(periodRate, n), nil
+}
+
+// annualizeRate n 为每年期数，非整数时实际年化按小数次幂近似计算
+func annualizeRate(periodRate, n decimal.Decimal) APR {
+	effective := decimal.NewFromFloat(math.Pow(periodRate.Add(one).InexactFloat64(), n.InexactFloat64()) - 1)
+	if n.IsInteger() {
+		effective = periodRate.Add(one).Pow(n).Sub(one)
+	}
+	return APR{
+		PeriodRate: periodRate,
+		Nominal:    periodRate.Mul(n).Round(10),
+		Effective:  effective.Round(10),
+	}
+}
+
+// ScheduleAPR 按 IRR 法计算还款计划的年化利率：放款本金在起息日流出，
+// 之后每期在还款日流入本金、利息及服务费（Fee.GetFee），已删除的计划不计入。
+// 各笔现金流按起息日到还款日的年化天数（产品计息基准）换算成期数折现，
+// 到期一次还本付息、不规则首期等不等间隔的计划也能得到正确的期利率
+func ScheduleAPR(l *LoanExtra) (APR, error) {
+	schedules := make([]Schedule, 0, len(l.Schedules))
+	start := time.Time{}
+	for _, s := range l.Schedules {
+		if s.Status == ScheduleRemoved {
+			continue
+		}
+		schedules = append(schedules, s)
+		if !s.AccrualStart.IsZero() && (start.IsZero() || s.AccrualStart.Before(start)) {
+			start = s.AccrualStart
+		}
+	}
+	if start.IsZero() {
+		start = l.CreatedAt
+	}
+	n, err := periodsPerYear(l.Product.PeriodType, l.Product.PeriodLength)
+	if err != nil {
+		return APR{}, err
+	}
+
+	times := make([]float64, 0, len(schedules)+1)
+	cf := make([]float64, 0, len(schedules)+1)
+	times = append(times, 0)
+	cf = append(cf, l.Principal.Neg().InexactFloat64())
+	for _, s := range schedules {
+		ratio, err := l.Product.yearFraction(start, s.DueDate)
+		if err != nil {
+			return APR{}, err
+		}
+		f := s.Principal.Add(s.Interest)
+		for _, fee := range s.ServiceFee {
+			f = f.Add(fee.GetFee(s.Principal))
+		}
+		times = append(times, ratio.Mul(n).InexactFloat64())
+		cf = append(cf, f.InexactFloat64())
+	}
+	r, ok := solveIRR(times, cf, 0.01)
+	if !ok {
+		return APR{}, ErrIRRNotConverge
+	}
+	return annualizeRate(decimal.NewFromFloat(r).Round(10), n), nil
+}
+
+// IRR 计算等间隔现金流的内部收益率（期利率），flows[0] 为第 0 期（通常是放款，记为负数）
+func IRR(flows []decimal.Decimal) (decimal.Decimal, error) {
+	if len(flows) < 2 {
+		return decimal.Zero, ErrIRRNotConverge
+	}
+	times := make([]float64, len(flows))
+	cf := make([]float64, len(flows))
+	for i, f := range flows {
+		times[i] = float64(i)
+		cf[i] = f.InexactFloat64()
+	}
+	r, ok := solveIRR(times, cf, 0.01)
+	if !ok {
+		return decimal.Zero, ErrIRRNotConverge
+	}
+	return decimal.NewFromFloat(r).Round(10), nil
+}
+
+// CashFlow 带日期的现金流，流出记负数、流入记正数
+type CashFlow struct {
+	At     time.Time
+	Amount decimal.Decimal
+}
+
+// XIRR 计算不等间隔现金流的年化内部收益率，按实际天数/365 折现，以最早一笔现金流的日期为基准
+func XIRR(flows []CashFlow) (decimal.Decimal, error) {
+	if len(flows) < 2 {
+		return decimal.Zero, ErrNoCashFlow
+	}
+	t0 := flows[0].At
+	for _, f := range flows {
+		if f.At.Before(t0) {
+			t0 = f.At
+		}
+	}
+	years := make([]float64, len(flows))
+	cf := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.At.Sub(t0).Hours() / 24 / 365
+		cf[i] = f.Amount.InexactFloat64()
+	}
+	r, ok := solveIRR(years, cf, 0.1)
+	if !ok {
+		return decimal.Zero, ErrIRRNotConverge
+	}
+	return decimal.NewFromFloat(r).Round(10), nil
+}
+
+// RealizedYield 按实际还款记录（Repayment.RepayAt、TotalAmount 扣除退款）计算贷款截至估值日 asOf 的实现收益率（XIRR），
+// 放款按 Loan.CreatedAt 计，失败、取消以及 asOf 之后的还款不计入。
+// 未结清的贷款把 asOf 当日的账面余额（见 BalanceAsOf）作为最后一笔流入，已结清的贷款余额为 0
+func RealizedYield(l *LoanExtra, asOf time.Time) (decimal.Decimal, error) {
+	flows := []CashFlow{{At: l.CreatedAt, Amount: l.Principal.Neg()}}
+	for _, r := range l.Repayments {
+		if r.Status == RepayFailed || r.Status == RepayCanceled || CompareDate(r.RepayAt, asOf) > 0 {
+			continue
+		}
+		flows = append(flows, CashFlow{At: r.RepayAt, Amount: r.TotalAmount.Sub(r.RefundAmount)})
+	}
+	balance, err := l.BalanceAsOf(asOf)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if balance.IsPositive() {
+		flows = append(flows, CashFlow{At: asOf, Amount: balance})
+	}
+	return XIRR(flows)
+}
+
+// BalanceAsOf 返回贷款在 asOf 当日的账面余额：已到期未结清各期的剩余应还金额，
+// 加上未到期各期的本金和截至 asOf 按计息区间比例计提的利息
+func (l *LoanExtra) BalanceAsOf(asOf time.Time) (decimal.Decimal, error) {
+	balance := decimal.Zero
+	for _, s := range l.Schedules {
+		if s.Status == SchedulePaid || s.Status == ScheduleRemoved {
+			continue
+		}
+		if CompareDate(s.AccrualEnd, asOf) <= 0 {
+			balance = balance.Add(s.TotalPayment.Sub(s.TotalPaymentPaid))
+			continue
+		}
+		balance = balance.Add(s.Principal)
+		if CompareDate(s.AccrualStart, asOf) >= 0 {
+			continue
+		}
+		elapsed, err := l.Product.yearFraction(s.AccrualStart, asOf)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		whole, err := l.Product.yearFraction(s.AccrualStart, s.AccrualEnd)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if whole.IsPositive() {
+			balance = balance.Add(s.Interest.Mul(elapsed).Div(whole))
+		}
+	}
+	return balance, nil
+}
+
+// ContractualYield 按还款计划的还款日和应还金额（含服务费）计算合同收益率（XIRR），用于与 RealizedYield 对比
+func ContractualYield(l *LoanExtra) (decimal.Decimal, error) {
+	flows := []CashFlow{{At: l.CreatedAt, Amount: l.Principal.Neg()}}
+	for _, s := range l.Schedules {
+		if s.Status == ScheduleRemoved {
+			continue
+		}
+		f := s.Principal.Add(s.Interest)
+		for _, fee := range s.ServiceFee {
+			f = f.Add(fee.GetFee(s.Principal))
+		}
+		flows = append(flows, CashFlow{At: s.DueDate, Amount: f})
+	}
+	return XIRR(flows)
+}
+
+// solveIRR 求使现金流净现值为 0 的利率，times 为各笔现金流距基准时点的期数（可为小数）
+func solveIRR(times, cf []float64, guess float64) (float64, bool) {
+	npv := func(r float64) (float64, float64) {
+		var v, dv float64
+		for i, c := range cf {
+			d := math.Pow(1+r, times[i])
+			v += c / d
+			dv -= times[i] * c / (d * (1 + r))
+		}
+		return v, dv
+	}
+	return solveRate(npv, guess)
+}
+
+// solveRate 先用牛顿法求根，不收敛时回落到二分法
+func solveRate(f func(r float64) (float64, float64), guess float64) (float64, bool) {
+	r := guess
+	for i := 0; i < irrMaxIter; i++ {
+		v, dv := f(r)
+		if math.Abs(v) < irrTolerance {
+			return r, true
+		}
+		if dv == 0 || math.IsNaN(dv) {
+			break
+		}
+		next := r - v/dv
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-r) < irrTolerance {
+			return next, true
+		}
+		r = next
+	}
+
+	lo, hi := -0.999999, 10.0
+	vlo, _ := f(lo)
+	vhi, _ := f(hi)
+	if vlo*vhi > 0 {
+		return 0, false
+	}
+	for i := 0; i < irrMaxIter; i++ {
+		mid := (lo + hi) / 2
+		v, _ := f(mid)
+		if math.Abs(v) < irrTolerance || (hi-lo)/2 < irrTolerance {
+			return mid, true
+		}
+		if v*vlo > 0 {
+			lo, vlo = mid, v
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, true
+}
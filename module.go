@@ -35,6 +35,7 @@ type LoanExtra struct {
 	Schedules      []Schedule      `db:"schedules"`       // 生成的计划表
 	Repayments     []Repayment     `db:"repayments"`      // 已发生的还款事件
 	OverdueRecords []OverdueRecord `db:"overdue_records"` // 逾期记录
+	APR            decimal.Decimal `db:"apr"`             // 按 IRR 折算的实际年化利率（按期复利），用于披露（等本等息等名义利率失真的产品）
 
 }
 type Loan struct {
@@ -45,6 +46,8 @@ type Loan struct {
 	Product      *Product        `db:"product"`       //对应的金融产品
 	CreatedAt    time.Time       `db:"created_at"`
 	Statue       LoanStatus      `db:"statue"`
+	Rate         decimal.Decimal `db:"rate"`        // 当前执行利率（年化）
+	RepricedAt   time.Time       `db:"repriced_at"` // 最近一次重定价日
 }
 
 // OverdueRecord 逾期记录（值对象）
@@ -143,7 +146,7 @@ func (l *LoanExtra) IsFullyPaid() bool {
 
 // PeriodRate 返回已经换算好的期别利率（领域服务可调用）
 func (l *LoanExtra) PeriodRate() (decimal.Decimal, error) {
-	return AnnualToPeriodRate(l.Product.Interest, l.Product.PeriodType, l.Product.DayCountConv)
+	return l.CurrentProduct().periodRate()
 }
 
 func (l *LoanExtra) HasOverdue() bool {
@@ -153,6 +156,7 @@ func (l *LoanExtra) HasOverdue() bool {
 type OverdueRecord struct {
 	ID             int64           `db:"id"`
 	LoanID         int64           `db:"loan_id"`
+	ScheduleID     int64           `db:"schedule_id"` // 对应的还款计划，计划被替换时随之迁移
 	Period         int             `db:"period"`
 	StartDate      time.Time       `db:"start_date"`
 	DaysOver       int             `db:"days_over"`
@@ -198,27 +202,66 @@ func (o *OverdueRecord) TryToPay(amount decimal.Decimal) decimal.Decimal {
 }
 
 type Product struct {
-	ID             int64           `db:"id"  json:"id,omitempty"`
-	Name           string          `db:"name" json:"name,omitempty"`
-	Interest       decimal.Decimal `db:"interest" json:"interest"`
-	MinPrinciple   decimal.Decimal `db:"min_principle" json:"min_principle"`
-	MaxPrinciple   decimal.Decimal `db:"max_principle" json:"max_principle"`
-	MinPeriods     int             `db:"min_periods" json:"min_periods,omitempty"`
-	MaxPeriods     int             `db:"max_periods" json:"max_periods,omitempty"`
-	RepayType      RepayType       `db:"repay_type" json:"repay_type,omitempty"`
-	RollConvention RollConvention  `db:"roll_convention" json:"roll_convention,omitempty"`
-	DayCountConv   DayCountConv    `db:"day_count_conv" json:"day_count_conv,omitempty"`
-	PeriodType     PeriodType      `db:"period_type" json:"period_type,omitempty"`
-	GraceTerm      int             `db:"grace_term" json:"grace_term,omitempty"` //宽限期，用于支持气球贷
-	GraceDay       int             `db:"grace_day" json:"grace_day,omitempty"`   //允许的延迟还款日，在这几天内还款不算逾期
-	Penalty        decimal.Decimal `db:"penalty" json:"penalty"`                 //逾期利率 TODO: 逾期利率支持阶梯
-	DefaultRate    decimal.Decimal `db:"default_rate" json:"default_rate"`       //违约金，这玩意按道理也是该支持阶梯的
-	Fees           []Fee           `db:"fees" json:"fees,omitempty"`
-	Info           string          `db:"info" json:"info,omitempty"`
-	extra          string          `db:"extra" json:"extra,omitempty"`
-	Status        ProductStatues  `db:"status" json:"status,omitempty"`
-	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
-	UpdatedAt      time.Time       `db:"updated_at" json:"updated_at"`
+	ID                   int64              `db:"id"  json:"id,omitempty"`
+	Name                 string             `db:"name" json:"name,omitempty"`
+	Interest             decimal.Decimal    `db:"interest" json:"interest"`
+	FloatingRate         *FloatingRate      `db:"floating_rate" json:"floating_rate,omitempty"` //浮动利率定义，为空时按 Interest 固定计息
+	MinPrinciple         decimal.Decimal    `db:"min_principle" json:"min_principle"`
+	MaxPrinciple         decimal.Decimal    `db:"max_principle" json:"max_principle"`
+	MinPeriods           int                `db:"min_periods" json:"min_periods,omitempty"`
+	MaxPeriods           int                `db:"max_periods" json:"max_periods,omitempty"`
+	RepayType            RepayType          `db:"repay_type" json:"repay_type,omitempty"`
+	RollConvention       RollConvention     `db:"roll_convention" json:"roll_convention,omitempty"`
+	DayCountConv         DayCountConv       `db:"day_count_conv" json:"day_count_conv,omitempty"`
+	ActualPeriodInterest bool               `db:"actual_period_interest" json:"actual_period_interest,omitempty"` //按每期实际起止日计息，等额本息的供款保持不变，尾差计入最后一期
+	PeriodType           PeriodType         `db:"period_type" json:"period_type,omitempty"`
+	PeriodLength         int                `db:"period_length" json:"period_length,omitempty"`             //自定义期别 N_DAYS/N_MONTHS 的长度
+	RepayDay             int                `db:"repay_day" json:"repay_day,omitempty"`                     //固定还款日（每月几号），0 表示按放款日对应日还款，仅按月/年计期时生效
+	StubType             StubType           `db:"stub_type" json:"stub_type,omitempty"`                     //设置固定还款日后首期不规则的处理方式，为空时按短首期处理
+	EndOfMonth           bool               `db:"end_of_month" json:"end_of_month,omitempty"`               //月末规则：放款日为月末时每期都在月末还款
+	Calendar             HolidayProvider    `db:"-" json:"-"`                                               //产品使用的营业日日历，为空时使用 Config.Holiday
+	AccrualUnadjusted    bool               `db:"accrual_unadjusted" json:"accrual_unadjusted,omitempty"`   //计息期按未调整的日期计算，滚动惯例只调整还款日（ISDA 惯例）
+	GraceTerm            int                `db:"grace_term" json:"grace_term,omitempty"`                   //宽限期，前若干期只付息不还本
+	BalloonTerm          int                `db:"balloon_term" json:"balloon_term,omitempty"`               //气球贷名义摊还期数，按该期数计算每期本金，剩余本金在最后一期归还
+	BalloonRatio         decimal.Decimal    `db:"balloon_ratio" json:"balloon_ratio"`                       //气球贷尾款占本金的比例，BalloonTerm 未设置时生效
+	StepRate             decimal.Decimal    `db:"step_rate" json:"step_rate"`                               //阶梯供款增长率，每 StepInterval 期供款按该比例递增
+	StepInterval         int                `db:"step_interval" json:"step_interval,omitempty"`             //阶梯供款的递增间隔期数
+	PaymentSteps         []PaymentStep      `db:"payment_steps" json:"payment_steps,omitempty"`             //显式分段供款，优先于 StepRate，未覆盖的期数按等额本息摊还剩余本金
+	InterestAllocation   InterestAllocation `db:"interest_allocation" json:"interest_allocation,omitempty"` //利息分摊方式，为空时按剩余本金计息
+	GraceDay             int                `db:"grace_day" json:"grace_day,omitempty"`                     //允许的延迟还款日，在这几天内还款不算逾期，截止日见 GraceDeadline
+	Penalty              decimal.Decimal    `db:"penalty" json:"penalty"`                                   //逾期利率 TODO: 逾期利率支持阶梯
+	DefaultRate          decimal.Decimal    `db:"default_rate" json:"default_rate"`                         //违约金，这玩意按道理也是该支持阶梯的
+	Fees                 []Fee              `db:"fees" json:"fees,omitempty"`
+	Info                 string             `db:"info" json:"info,omitempty"`
+	extra                string             `db:"extra" json:"extra,omitempty"`
+	Status               ProductStatues     `db:"status" json:"status,omitempty"`
+	CreatedAt            time.Time          `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time          `db:"updated_at" json:"updated_at"`
+}
+
+// holidays 返回产品使用的营业日日历
+func (s *Product) holidays() HolidayProvider {
+	if s.Calendar != nil {
+		return s.Calendar
+	}
+	return cfg.Holiday
+}
+
+// yearFraction 按产品的日期计算惯例和营业日日历计算区间的年化分数
+func (s *Product) yearFraction(start, end time.Time) (decimal.Decimal, error) {
+	return yearFraction(start, end, s.DayCountConv, s.holidays(), s.PeriodType, s.PeriodLength)
+}
+
+// PaymentStep 阶梯供款中的一段：连续 Periods 期每期供款 Amount
+type PaymentStep struct {
+	Periods int             `db:"periods" json:"periods"`
+	Amount  decimal.Decimal `db:"amount" json:"amount"`
+}
+
+// PlanItem 自定义还款计划中的一期：约定还款日和应还本金
+type PlanItem struct {
+	DueDate   time.Time       `db:"due_date" json:"due_date"`
+	Principal decimal.Decimal `db:"principal" json:"principal"`
 }
 
 func (s *Product) GetName() string {
@@ -299,6 +342,8 @@ type Schedule struct {
 	LoanID           int64           `db:"loan_id"`
 	Period           int             `db:"period"`             // 第几期（从 1 开始）
 	DueDate          time.Time       `db:"due_date"`           // 还款日
+	AccrualStart     time.Time       `db:"accrual_start"`      // 计息起始日
+	AccrualEnd       time.Time       `db:"accrual_end"`        // 计息截止日，产品开启 AccrualUnadjusted 时可能与还款日不同
 	Principal        decimal.Decimal `db:"principal"`          // 本期应还本金
 	Interest         decimal.Decimal `db:"interest"`           // 本期应还利息
 	ServiceFee       []Fee           `db:"service_fee"`        // 本期服务费（可扩展为多项费用）
@@ -334,7 +379,7 @@ func NewSchedule(id, loanId int64, period int, dueDate time.Time, principal, int
 
 // TryToPay 仅供正常还款使用，不考虑提前还款场景
 func (s *Schedule) TryToPay(amount decimal.Decimal) decimal.Decimal {
-	if s.Principal == decimal.Zero {
+	if s.TotalPayment.IsZero() {
 		s.Status = SchedulePaid
 		return amount
 	}
@@ -0,0 +1,73 @@
+package loancalc
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RunOverdueBatch 日终跑批：截至 asOf 已过宽限期（见 Product.GraceDeadline）仍未结清的期次标记为逾期，
+// 并按 Product.Penalty 对未还金额计提罚息，罚息从还款日起算。
+// 逾期记录的 UpdatedAt 即罚息已计提到的日期，每次只补计 UpdatedAt 到 asOf 之间的罚息，
+// 因此同一天重复跑批不会重复计提，期间发生部分还款时后续罚息按新的未还金额计算。
+// 逾期记录按还款计划 ID 关联，期次重新编号不会串用记录；计划已结清或已删除时记录不再计提，标记为已结清
+func RunOverdueBatch(l *LoanExtra, asOf time.Time, gen IDGenerator) error {
+	// 罚息按日计提，一律按日历日期计算，跑批时刻不影响计提天数
+	asOf = dateOnly(asOf)
+	for i := range l.Schedules {
+		s := &l.Schedules[i]
+		if s.Status == SchedulePaid || s.Status == ScheduleRemoved {
+			if o := l.overdueRecord(s.ID); o != nil && (o.Statue == OverdueStatusAccruing || o.Statue == OverdueStatusPartial) {
+				o.Statue = OverdueStatusCleared
+			}
+			continue
+		}
+		if CompareDate(asOf, l.Product.GraceDeadline(s.DueDate)) <= 0 {
+			continue
+		}
+		s.Overdue = true
+
+		o := l.overdueRecord(s.ID)
+		if o == nil {
+			o = NewOverdueRecord(gen(), l.ID, s.Period, 0, l.Product.Penalty, decimal.Zero)
+			o.ScheduleID = s.ID
+			o.StartDate = dateOnly(s.DueDate)
+			o.UpdatedAt = dateOnly(s.DueDate)
+			l.AddOverdueRecord(*o)
+			o = &l.OverdueRecords[len(l.OverdueRecords)-1]
+		}
+		if o.Statue == OverdueStatusWaived || CompareDate(asOf, o.UpdatedAt) <= 0 {
+			continue
+		}
+		ratio, err := l.Product.yearFraction(dateOnly(o.UpdatedAt), asOf)
+		if err != nil {
+			return err
+		}
+		unpaid := s.TotalPayment.Sub(s.TotalPaymentPaid)
+		o.PenaltyAccrued = o.PenaltyAccrued.Add(unpaid.Mul(o.Rate).Mul(ratio))
+		o.DaysOver = daysBetween(s.DueDate, asOf)
+		o.UpdatedAt = asOf
+		// 本金未结清，罚息仍在计提
+		o.Statue = OverdueStatusAccruing
+	}
+	return nil
+}
+
+// overdueRecord 返回指定还款计划最近一条逾期记录，没有时返回 nil
+func (l *LoanExtra) overdueRecord(scheduleID int64) *OverdueRecord {
+	for i := len(l.OverdueRecords) - 1; i >= 0; i-- {
+		if l.OverdueRecords[i].ScheduleID == scheduleID {
+			return &l.OverdueRecords[i]
+		}
+	}
+	return nil
+}
+
+// reassignOverdue 还款计划被同一期的新计划替换时，把逾期记录迁移到新计划上，罚息接着原有进度计提
+func (l *LoanExtra) reassignOverdue(from, to *Schedule) {
+	for i := range l.OverdueRecords {
+		if o := &l.OverdueRecords[i]; o.ScheduleID == from.ID {
+			o.ScheduleID, o.Period = to.ID, to.Period
+		}
+	}
+}
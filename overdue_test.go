@@ -0,0 +1,78 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRunOverdueBatchRecordsFollowSchedules(t *testing.T) {
+	gen := startTest(t, Config{})
+	p := &Product{
+		ID:           1,
+		Interest:     decimal.NewFromFloat(0.12),
+		Penalty:      decimal.NewFromFloat(0.36),
+		RepayType:    RepayTypeEqualInstallment,
+		PeriodType:   PeriodMonth,
+		DayCountConv: BONDBASIS,
+	}
+	e := &Engine{handlers: map[int64]*handler{}}
+	if _, err := e.RegisterProduct(p); err != nil {
+		t.Fatal(err)
+	}
+	l, err := e.BuildSchedules(Loan{ID: 1, Principal: decimal.NewFromInt(3000), TotalPeriods: 3, Product: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := l.Schedules[0]
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+
+	// 第 1 期 2-15 到期，2-20 跑批计 5 天罚息，同一天重跑不重复计提
+	for i := 0; i < 2; i++ {
+		if err := RunOverdueBatch(l, day(2, 20), gen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l.OverdueRecords) != 1 {
+		t.Fatalf("got %d overdue records, want 1", len(l.OverdueRecords))
+	}
+	want := first.TotalPayment.Mul(decimal.NewFromFloat(0.36)).Mul(decimal.NewFromInt(5)).Div(decimal.NewFromInt(360)).Round(6)
+	if o := l.OverdueRecords[0]; o.ScheduleID != first.ID || !o.PenaltyAccrued.Round(6).Equal(want) {
+		t.Fatalf("record = schedule %d penalty %s, want schedule %d penalty %s", o.ScheduleID, o.PenaltyAccrued, first.ID, want)
+	}
+
+	// 结清后记录关闭，不再计提
+	l.Schedules[0].Status = SchedulePaid
+	if err := RunOverdueBatch(l, day(2, 25), gen); err != nil {
+		t.Fatal(err)
+	}
+	if o := l.OverdueRecords[0]; o.Statue != OverdueStatusCleared || !o.PenaltyAccrued.Round(6).Equal(want) {
+		t.Errorf("paid schedule record = %s penalty %s, want %s penalty %s", o.Statue, o.PenaltyAccrued, OverdueStatusCleared, want)
+	}
+
+	// 减额提前还款后新计划从第 1 期重新编号，新的第 1 期逾期时应另起记录
+	if _, err := prepayPaymentReduction(l, decimal.NewFromInt(500), gen); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunOverdueBatch(l, day(3, 20), gen); err != nil {
+		t.Fatal(err)
+	}
+	if o := l.OverdueRecords[0]; !o.PenaltyAccrued.Round(6).Equal(want) {
+		t.Errorf("stale record reused: penalty %s, want %s", o.PenaltyAccrued, want)
+	}
+	overdue := 0
+	for _, s := range l.Schedules {
+		if s.Status != ScheduleUnpaid || !s.Overdue {
+			continue
+		}
+		overdue++
+		o := l.overdueRecord(s.ID)
+		if o == nil || o.ID == l.OverdueRecords[0].ID || !o.StartDate.Equal(dateOnly(s.DueDate)) {
+			t.Errorf("period %d has no record of its own", s.Period)
+		}
+	}
+	if overdue == 0 {
+		t.Error("no overdue period after renumbering")
+	}
+}
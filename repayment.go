@@ -53,7 +53,7 @@ func NormalRepay(l *LoanExtra, amount decimal.Decimal, generator IDGenerator) (r
 	if start == idxNotFound {
 		start = currentIdx
 	}
-	//挂逾期的任务交给每天定时的跑批任务
+	//挂逾期的任务交给每天定时的跑批任务，见 RunOverdueBatch
 	for i := start; i <= currentIdx; i++ {
 		remaining = l.Schedules[i].TryToPay(remaining)
 		if remaining.IsZero() && l.Schedules[i].Status != SchedulePaid {
@@ -118,19 +118,60 @@ func PreRepay(l *LoanExtra, amount decimal.Decimal,
 	return remaining, nil
 }
 
+// PayoffQuote 提前结清报价
+type PayoffQuote struct {
+	OutstandingPrincipal decimal.Decimal // 剩余本金
+	RemainingPayment     decimal.Decimal // 计划中未还各期的本息合计
+	InterestRebate       decimal.Decimal // 提前结清免收的未到期利息
+	DefaultFee           decimal.Decimal // 违约金
+	Total                decimal.Decimal // 结清应付总额
+}
+
+// SettlementQuote 计算提前结清报价，未到期利息的退还方式与产品的利息分摊方式一致
+func SettlementQuote(l *LoanExtra) PayoffQuote {
+	q := PayoffQuote{OutstandingPrincipal: l.OutstandingPrincipal()}
+	remainingInterest := decimal.Zero
+	totalInterest := decimal.Zero
+	var periods, remaining int64
+	for _, s := range l.Schedules {
+		if s.Status == ScheduleRemoved {
+			continue
+		}
+		periods++
+		totalInterest = totalInterest.Add(s.Interest)
+		if s.Status == ScheduleUnpaid {
+			remaining++
+			remainingInterest = remainingInterest.Add(s.Interest)
+			q.RemainingPayment = q.RemainingPayment.Add(s.Principal).Add(s.Interest)
+		}
+	}
+	if l.Product.InterestAllocation == AllocationRuleOf78 {
+		q.InterestRebate = RuleOf78Rebate(totalInterest, periods, remaining)
+	} else {
+		q.InterestRebate = remainingInterest
+	}
+	q.DefaultFee = q.OutstandingPrincipal.Mul(l.Product.DefaultRate)
+	q.Total = q.RemainingPayment.Sub(q.InterestRebate).Add(q.DefaultFee)
+	return q
+}
+
 // 真正的提前还款内核
 func prepayCore(l *LoanExtra, money decimal.Decimal,
 	gen IDGenerator, strategy PrepayStrategy) (decimal.Decimal, error) {
 
-	outstandingPrincipal := l.OutstandingPrincipal()
-	if money.Cmp(outstandingPrincipal.Mul(ONE.Add(l.Product.DefaultRate))) >= 0 {
+	quote := SettlementQuote(l)
+	if money.Cmp(quote.Total) >= 0 {
 		// 一次性结清
 		for i := 0; i < len(l.Schedules); i++ {
 			l.Schedules[i].Status = SchedulePaid
 		}
-		return money.Sub(outstandingPrincipal), nil
+		return money.Sub(quote.Total), nil
 	}
 
+	// 一次性还本付息只有一期，缩期和减供都退化为冲减这一期
+	if l.Product.RepayType == RepayTypeBullet {
+		return prepayBullet(l, money, gen)
+	}
 	if strategy == PrepayTermReduction {
 		return prepayTermReduction(l, money, gen)
 	}
@@ -150,31 +191,62 @@ func prepayTermReduction(l *LoanExtra, money decimal.Decimal, gen IDGenerator) (
 			s.Status = SchedulePaid
 		} else {
 			newS := NewSchedule(gen(), s.LoanID, s.Period, s.DueDate, s.Principal, s.Interest, s.ServiceFee)
+			newS.AccrualStart, newS.AccrualEnd = s.AccrualStart, s.AccrualEnd
 			r := ONE.Add(l.Product.DefaultRate)
 			x := money.Div(r)
 			newS.Principal = newS.Principal.Sub(x)
 			newS.Interest = newS.Principal.Mul(l.Product.Interest)
-			l.AddSchedule(*newS)
+			l.reassignOverdue(s, newS)
 			s.Status = ScheduleRemoved
+			l.AddSchedule(*newS)
 			break
 		}
 	}
 	return money, nil
 }
 
-/* 减额：保持期数，重新生成等额本息/等额本金计划 */
+/* 到期一次性还本付息：冲减到期本金，并扣减被冲减本金从今天到到期日的利息 */
+func prepayBullet(l *LoanExtra, money decimal.Decimal, gen IDGenerator) (decimal.Decimal, error) {
+	for i := len(l.Schedules) - 1; i >= 0; i-- {
+		s := &l.Schedules[i]
+		if s.Status == SchedulePaid || s.Status == ScheduleRemoved {
+			continue
+		}
+		x := money.Div(ONE.Add(l.Product.DefaultRate))
+		interest := s.Interest
+		if now := cfg.Clock.Now(); CompareDate(now, s.DueDate) < 0 {
+			ratio, err := l.Product.yearFraction(now, s.DueDate)
+			if err != nil {
+				return money, err
+			}
+			interest = interest.Sub(x.Mul(l.CurrentProduct().Interest).Mul(ratio))
+		}
+		newS := NewSchedule(gen(), s.LoanID, s.Period, s.DueDate, s.Principal.Sub(x), interest, s.ServiceFee)
+		newS.AccrualStart, newS.AccrualEnd = s.AccrualStart, s.AccrualEnd
+		l.reassignOverdue(s, newS)
+		s.Status = ScheduleRemoved
+		l.AddSchedule(*newS)
+		return decimal.Zero, nil
+	}
+	return money, ErrNoScheduleFound
+}
+
+/* 减额：保持期数，按产品还款方式重新生成计划 */
 func prepayPaymentReduction(l *LoanExtra, money decimal.Decimal, gen IDGenerator) (decimal.Decimal, error) {
 	newPrincipal := l.OutstandingPrincipal().Sub(money)
 	periods := int64(l.OutstandingPeriods())
 
+	product := l.CurrentProduct()
 	var newSchedules []Schedule
-	switch l.Product.RepayType {
-	case RepayTypeEqualInstallment:
-		newSchedules, _ = AnnuitySchedule(l.ID, newPrincipal, periods, l.Product, gen)
-	case RepayTypeEqualPrincipal:
-		newSchedules, _ = EqualPrincipalSchedule(l.ID, newPrincipal, periods, l.Product, gen)
-	default:
-		return money, ErrUnSupportRepayType
+	var err error
+	if product.RepayType == RepayTypeCustom {
+		// 自定义计划没有公式可循，保留原约定日期，按比例缩减各期本金
+		newSchedules, err = CustomSchedule(l.ID, newPrincipal, cfg.Clock.Now(), scaledPlan(l, newPrincipal), product, gen)
+	} else {
+		newSchedules, err = buildSchedules(l.ID, newPrincipal, periods, product, gen)
+	}
+	if err != nil {
+		return money, err
 	}
 	// 把旧计划全部标记删除
 	for i := 0; i < len(l.Schedules); i++ {
@@ -189,6 +261,27 @@ func prepayPaymentReduction(l *LoanExtra, money decimal.Decimal, gen IDGenerator
 	}
 	return decimal.Zero, nil
 }
+
+// scaledPlan 把未还各期本金按比例缩放到 newPrincipal，尾差计入最后一期
+func scaledPlan(l *LoanExtra, newPrincipal decimal.Decimal) []PlanItem {
+	outstanding := l.OutstandingPrincipal()
+	plan := make([]PlanItem, 0, l.OutstandingPeriods())
+	allocated := decimal.Zero
+	for _, s := range l.Schedules {
+		if s.Status != ScheduleUnpaid {
+			continue
+		}
+		p := s.Principal.Mul(newPrincipal).Div(outstanding)
+		allocated = allocated.Add(p)
+		plan = append(plan, PlanItem{DueDate: s.DueDate, Principal: p})
+	}
+	if len(plan) > 0 {
+		last := &plan[len(plan)-1]
+		last.Principal = last.Principal.Add(newPrincipal.Sub(allocated))
+	}
+	return plan
+}
+
 func CompareDate(t1, t2 time.Time) int {
 	y1, m1, d1 := t1.Date()
 	y2, m2, d2 := t2.Date()
@@ -7,35 +7,123 @@ import (
 
 type HolidayFunc func(time.Time) bool
 
-// NextPeriodDate 使用可注入的 HolidayProvider 实现跳期
-func NextPeriodDate(last time.Time, period PeriodType, roll RollConvention) (time.Time, error) {
+// NextPeriodDate 使用可注入的 HolidayProvider 实现跳期，length 为自定义期别（N_DAYS/N_MONTHS）的长度
+func NextPeriodDate(last time.Time, period PeriodType, length int, roll RollConvention) (time.Time, error) {
 	// 由于内部 NextPeriodDate 需要一个 isHoliday func，这里复用中国实现以共享节假日缓存
 	// 对于自定义 HolidayProvider，这里优先使用 provider，再回落内部实现
 	if _, ok := cfg.Holiday.(ChinaHolidayProvider); ok {
-		return nextPeriodDate(last, period, roll, func(t time.Time) bool { return ChinaHolidayProvider{}.IsHoliday(t) })
+		return nextPeriodDate(last, period, length, roll, func(t time.Time) bool { return ChinaHolidayProvider{}.IsHoliday(t) })
 	}
 	// 自定义 provider
-	return nextPeriodDate(last, period, roll, cfg.Holiday.IsHoliday)
+	return nextPeriodDate(last, period, length, roll, cfg.Holiday.IsHoliday)
 }
 
 // NextPeriodDate 核心函数：给定“上一期还款日”“期别单位”“跳期规则”，返回下一期还款日
-// period 支持 Day/BiWeek/Month/Year，与前面 DayCount 包共用同一套枚举
-func nextPeriodDate(last time.Time, period PeriodType, roll RollConvention, isHoliday HolidayFunc) (time.Time, error) {
-	var candidate time.Time
-	switch period {
+// period 与前面 DayCount 包共用同一套枚举，自定义期别（N_DAYS/N_MONTHS）的长度由 length 指定
+// 注意：由上一期（已调整的）还款日链式推算会产生漂移，生成整张计划时应使用 NthPeriodDate
+func nextPeriodDate(last time.Time, period PeriodType, length int, roll RollConvention, isHoliday HolidayFunc) (time.Time, error) {
+	candidate, err := nthPeriodDate(last, 1, period, DateRule{PeriodLength: length})
+	if err != nil {
+		return last, err
+	}
+	return applyRoll(candidate, roll, isHoliday), nil
+}
+
+// DateRule 按月推算还款日时的日期规则
+type DateRule struct {
+	AnchorDay    int  // 锚定的每月还款日，0 时取锚定日期本身的日
+	EndOfMonth   bool // 月末规则：锚定日期为月末时每期都取月末（2 月 28 日 → 3 月 31 日），仅在 AnchorDay 为 0 时生效
+	PeriodLength int  // 自定义期别（N_DAYS/N_MONTHS）的长度
+}
+
+func (s *Product) dateRule() DateRule {
+	return DateRule{AnchorDay: s.RepayDay, EndOfMonth: s.EndOfMonth, PeriodLength: s.PeriodLength}
+}
+
+// NthPeriodDate 返回从锚定日 anchor 起第 n 期的还款日。每期都由锚定日直接推算后再按 roll 调整，
+// 而不是由上一期调整后的日期链式推算，因此 1 月 31 日会依次得到 2 月 28/29 日、3 月 31 日
+func NthPeriodDate(anchor time.Time, n int, period PeriodType, roll RollConvention, rule DateRule) (time.Time, error) {
+	candidate, err := nthPeriodDate(anchor, n, period, rule)
+	if err != nil {
+		return anchor, err
+	}
+	return applyRoll(candidate, roll, cfg.Holiday.IsHoliday), nil
+}
+
+// nthPeriodDate 返回未经节假日调整的第 n 期日期
+func nthPeriodDate(anchor time.Time, n int, period PeriodType, rule DateRule) (time.Time, error) {
+	months, days, err := periodStep(period, rule.PeriodLength)
+	if err != nil {
+		return anchor, err
+	}
+	if months > 0 {
+		day := rule.AnchorDay
+		if day <= 0 {
+			day = anchor.Day()
+			if rule.EndOfMonth && isMonthEnd(anchor) {
+				day = 31
+			}
+		}
+		return withDay(addMonths(anchor, n*months), day), nil
+	}
+	return anchor.AddDate(0, 0, n*days), nil
+}
+
+// periodStep 返回一期对应的月数或天数（两者只有一个非 0），length 为自定义期别的长度
+func periodStep(pt PeriodType, length int) (months, days int, err error) {
+	switch pt {
 	case PeriodDay:
-		candidate = last.AddDate(0, 0, 1)
+		return 0, 1, nil
+	case PeriodWeek:
+		return 0, 7, nil
 	case PeriodBiWeek:
-		candidate = last.AddDate(0, 0, 14)
+		return 0, 14, nil
 	case PeriodMonth:
-		candidate = last.AddDate(0, 1, 0)
+		return 1, 0, nil
+	case PeriodQuarter:
+		return 3, 0, nil
+	case PeriodHalfYear:
+		return 6, 0, nil
 	case PeriodYear:
-		candidate = last.AddDate(1, 0, 0)
+		return 12, 0, nil
+	case PeriodNDays, PeriodNMonths:
+		if length <= 0 {
+			return 0, 0, fmt.Errorf("invalid period length %d for %s", length, pt)
+		}
+		if pt == PeriodNDays {
+			return 0, length, nil
+		}
+		return length, 0, nil
 	default:
-		return last, fmt.Errorf("unknown period type: %s", period)
+		return 0, 0, fmt.Errorf("unknown period type: %s", pt)
 	}
-	return applyRoll(candidate, roll, isHoliday), nil
 }
+
+// withDay 把日期调整到当月的第 day 日，当月没有该日时取月末
+func withDay(t time.Time, day int) time.Time {
+	y, m, _ := t.Date()
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(y, m, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// isMonthEnd 判断是否为当月最后一天
+func isMonthEnd(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Day() == 1
+}
+
+// addMonths 按月推移日期，目标月份没有对应日时取月末（如 1 月 31 日加一个月为 2 月 28/29 日）
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
 func applyRoll(t time.Time, roll RollConvention, isHoliday func(time.Time) bool) time.Time {
 	switch roll {
 	case Unadjusted:
@@ -64,6 +152,32 @@ func applyRoll(t time.Time, roll RollConvention, isHoliday func(time.Time) bool)
 			}
 		}
 		return t2
+	case ModPrecede:
+		origMonth := t.Month()
+		t2 := t
+
+		for isHoliday(t2) {
+			t2 = t2.AddDate(0, 0, -1)
+		}
+		if t2.Month() != origMonth {
+			t2 = t
+			for isHoliday(t2) {
+				t2 = t2.AddDate(0, 0, 1)
+			}
+		}
+		return t2
+	case Nearest:
+		if !isHoliday(t) {
+			return t
+		}
+		for d := 1; ; d++ {
+			if next := t.AddDate(0, 0, d); !isHoliday(next) {
+				return next
+			}
+			if prev := t.AddDate(0, 0, -d); !isHoliday(prev) {
+				return prev
+			}
+		}
 	}
 	return t
 }
@@ -10,6 +10,189 @@ type Decimal = decimal.Decimal
 
 var one = decimal.NewFromInt(1)
 
+// scheduleFees 从产品的费用模板复制出挂在某一期上的费用
+func scheduleFees(product *Product, scheduleId int64, idGenerator IDGenerator) []Fee {
+	fees := make([]Fee, len(product.Fees))
+	copy(fees, product.Fees)
+	for j := 0; j < len(fees); j++ {
+		fees[j].ID = idGenerator()
+		fees[j].Status = FeeStatusUnPaid
+		fees[j].ScheduleId = scheduleId
+	}
+	return fees
+}
+
+// buildSchedules 按产品的还款方式分派到对应的计划生成函数
+func buildSchedules(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	schedules, err := buildByRepayType(loanId, principal, periods, product, idGenerator)
+	if err != nil {
+		return nil, err
+	}
+	return allocateInterest(product, schedules)
+}
+
+func buildByRepayType(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	switch product.RepayType {
+	case RepayTypeEqualInstallment:
+		return AnnuitySchedule(loanId, principal, periods, product, idGenerator)
+	case RepayTypeEqualPrincipal:
+		return EqualPrincipalSchedule(loanId, principal, periods, product, idGenerator)
+	case RepayTypeInterestOnly:
+		return InterestOnlySchedule(loanId, principal, periods, product, idGenerator)
+	case RepayTypeBullet:
+		return BulletSchedule(loanId, principal, periods, product, idGenerator)
+	case RepayTypeFlatRate:
+		return FlatRateSchedule(loanId, principal, periods, product, idGenerator)
+	default:
+		return nil, ErrUnSupportRepayType
+	}
+}
+
+// scheduleDate 一期的还款日（按滚动惯例调整后）与计息截止日
+type scheduleDate struct {
+	Due     time.Time
+	Accrual time.Time // 产品设置 AccrualUnadjusted 时为未调整日期，否则与 Due 相同
+}
+
+// scheduleDates 从 start 开始推算 periods 期的日期。产品设置了固定还款日时，首期可能短于或长于一期，
+// 此时 stub 返回 true，首期利息需要按实际天数计算
+func scheduleDates(start time.Time, periods int64, product *Product) (dates []scheduleDate, stub bool, err error) {
+	dates = make([]scheduleDate, 0, periods)
+	// 每期都由锚定日直接推算，避免链式推算导致还款日漂移
+	anchor, offset := start, 1
+	if first, ok := firstRepayDate(start, product); ok {
+		stub = hasStub(start, product)
+		anchor, offset = first, 0
+	}
+	for i := int64(0); i < periods; i++ {
+		d, err := nthPeriodDate(anchor, int(i)+offset, product.PeriodType, product.dateRule())
+		if err != nil {
+			return nil, false, err
+		}
+		due := applyRoll(d, product.RollConvention, product.holidays().IsHoliday)
+		accrual := due
+		if product.AccrualUnadjusted {
+			accrual = d
+		}
+		dates = append(dates, scheduleDate{Due: due, Accrual: accrual})
+	}
+	return dates, stub, nil
+}
+
+// hasStub 判断从 start 起息时首期是否为不规则期（固定还款日与起息日不对应）
+func hasStub(start time.Time, product *Product) bool {
+	first, ok := firstRepayDate(start, product)
+	if !ok {
+		return false
+	}
+	months, _, _ := periodStep(product.PeriodType, product.PeriodLength)
+	return !first.Equal(addMonths(start, months))
+}
+
+// stampAccrual 按计息日期回填各期计息区间，首期从 start 起息
+func stampAccrual(schedules []Schedule, start time.Time, dates []scheduleDate) {
+	prev := start
+	for i := range schedules {
+		schedules[i].AccrualStart = prev
+		schedules[i].AccrualEnd = dates[i].Accrual
+		prev = dates[i].Accrual
+	}
+}
+
+// firstRepayDate 按固定还款日和首期处理方式确定首个（未调整的）还款日，未设置固定还款日时返回 false
+func firstRepayDate(start time.Time, product *Product) (time.Time, bool) {
+	months, _, _ := periodStep(product.PeriodType, product.PeriodLength)
+	if product.RepayDay <= 0 || months == 0 {
+		return time.Time{}, false
+	}
+	// 最近的固定还款日必须晚于放款日；多月一期时再顺延到该期的最后一个月
+	short := withDay(start, product.RepayDay)
+	if CompareDate(short, start) <= 0 {
+		short = withDay(addMonths(short, 1), product.RepayDay)
+	}
+	short = withDay(addMonths(short, months-1), product.RepayDay)
+	long := withDay(addMonths(short, months), product.RepayDay)
+	switch product.StubType {
+	case StubLong:
+		return long, true
+	case StubMerge:
+		regular := daysBetween(start, addMonths(start, months))
+		if daysBetween(start, short)*2 < regular {
+			return long, true
+		}
+	}
+	return short, true
+}
+
+// periodRate 返回产品按期别折算的期利率
+func (s *Product) periodRate() (Decimal, error) {
+	return annualToPeriodRate(s.Interest, s.PeriodType, s.PeriodLength, s.DayCountConv, s.holidays())
+}
+
+// periodInterest 计算一期利息：默认按统一的期利率 r；产品开启 ActualPeriodInterest 时或该期为不规则首期（stub）时，
+// 按上一还款日到本期还款日的实际区间和 DayCountConv 计息
+func periodInterest(balance, r Decimal, prev, due time.Time, product *Product, stub bool) (Decimal, error) {
+	if !product.ActualPeriodInterest && !stub {
+		return balance.Mul(r), nil
+	}
+	ratio, err := product.yearFraction(prev, due)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balance.Mul(product.Interest).Mul(ratio), nil
+}
+
+// allocateInterest 按产品的利息分摊方式重新分配各期利息，每期应还总额保持不变。
+// 78 法则只适用于每期供款相同的等额本息和等本等息，有宽限期、气球贷或阶梯供款的计划返回 ErrUnSupportRepayType
+func allocateInterest(product *Product, schedules []Schedule) ([]Schedule, error) {
+	if product.InterestAllocation != AllocationRuleOf78 {
+		return schedules, nil
+	}
+	level := product.RepayType == RepayTypeEqualInstallment || product.RepayType == RepayTypeFlatRate
+	if !level || product.GraceTerm > 0 || product.BalloonTerm > 0 || product.BalloonRatio.IsPositive() ||
+		len(product.PaymentSteps) > 0 || product.StepRate.IsPositive() {
+		return nil, ErrUnSupportRepayType
+	}
+	if err := AllocateRuleOf78(schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// AllocateRuleOf78 按 78 法则把计划中的总利息重新分摊到各期：第 k 期（共 n 期）分得 (n-k+1)/(n(n+1)/2)，
+// 本金随之调整以保持每期本息合计不变。前期分得的利息超过当期本息合计（本金为负）时返回 ErrNegativeAmortization，
+// 计划保持不变
+func AllocateRuleOf78(schedules []Schedule) error {
+	total := decimal.Zero
+	for _, s := range schedules {
+		total = total.Add(s.Interest)
+	}
+	n := int64(len(schedules))
+	digits := decimal.NewFromInt(n * (n + 1) / 2)
+	interests := make([]Decimal, n)
+	for k, s := range schedules {
+		interests[k] = total.Mul(decimal.NewFromInt(n - int64(k))).Div(digits)
+		if s.Principal.Add(s.Interest).LessThan(interests[k]) {
+			return ErrNegativeAmortization
+		}
+	}
+	for k := range schedules {
+		s := &schedules[k]
+		payment := s.Principal.Add(s.Interest)
+		s.Interest = interests[k]
+		s.Principal = payment.Sub(s.Interest)
+	}
+	return nil
+}
+
+// RuleOf78Rebate 按 78 法则计算提前结清时应退还的未到期利息：共 n 期、剩余 m 期时退还 m(m+1)/(n(n+1))
+func RuleOf78Rebate(totalInterest Decimal, periods, remaining int64) Decimal {
+	if periods <= 0 || remaining <= 0 {
+		return decimal.Zero
+	}
+	return totalInterest.Mul(decimal.NewFromInt(remaining * (remaining + 1))).Div(decimal.NewFromInt(periods * (periods + 1)))
+}
+
 func AnnuityPayment(principal Decimal, periods int64, rate Decimal) Decimal {
 
 	base1r := rate.Add(one)
@@ -23,41 +206,120 @@ func AnnuityPayment(principal Decimal, periods int64, rate Decimal) Decimal {
 // AnnuitySchedule 生成等额本息计划（注入 Clock/Holiday/Round）
 func AnnuitySchedule(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
 	schedules := make([]Schedule, 0, periods)
-	nextDate := func(t time.Time) time.Time {
-		n, _ := NextPeriodDate(t, product.PeriodType, product.RollConvention)
-		return n
+	start := cfg.Clock.Now()
+	dates, stub, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
 	}
-	t := cfg.Clock.Now()
 	g := int64(product.GraceTerm)
-	r, err := AnnualToPeriodRate(product.Interest, product.PeriodType, product.DayCountConv)
+	r, err := product.periodRate()
 	if err != nil {
 		return nil, err
 	}
 	pwt := AnnuityPayment(principal, periods-g, r)
+	switch {
+	case product.BalloonTerm > int(periods-g):
+		pwt = AnnuityPayment(principal, int64(product.BalloonTerm), r)
+	case product.BalloonRatio.IsPositive():
+		pwt = BalloonPayment(principal, periods-g, r, principal.Mul(product.BalloonRatio))
+	}
+	steps := GraduatedPayments(principal, periods-g, r, principal.Mul(product.BalloonRatio), product)
+	prev := start
 	for i := int64(1); i <= periods; i++ {
-		t = nextDate(t)
-		fees := make([]Fee, len(product.Fees))
-		copy(fees, product.Fees)
+		t := dates[i-1].Due
 		id := idGenerator()
-		for j := 0; j < len(fees); j++ {
-			fees[j].ID = idGenerator()
-			fees[j].Status = FeeStatusUnPaid
-			fees[j].ScheduleId = id
+		fees := scheduleFees(product, id, idGenerator)
+		interest, err := periodInterest(principal, r, prev, dates[i-1].Accrual, product, i == 1 && stub)
+		if err != nil {
+			return nil, err
 		}
-		interest := principal.Mul(r)
+		prev = dates[i-1].Accrual
 		if i <= g {
 			s := NewSchedule(id, loanId, int(i), t, decimal.Zero, interest, fees)
 			schedules = append(schedules, *s)
 			continue
 		}
-		p := pwt.Sub(interest)
+		payment := pwt
+		if steps != nil {
+			// 阶梯供款前期可能不足以覆盖利息，此时本金为负（负摊还）
+			payment = steps[i-g-1]
+		}
+		p := payment.Sub(interest)
+		if i == 1 && stub {
+			// 不规则首期按正常一期拆分本金，利息按实际天数另计
+			p = payment.Sub(principal.Mul(r))
+		}
+		if i == periods {
+			// 最后一期结清剩余本金（含气球贷尾款）
+			p = principal
+		}
 		principal = principal.Sub(p)
 		s := NewSchedule(id, loanId, int(i), t, p, interest, fees)
 		schedules = append(schedules, *s)
 	}
+	stampAccrual(schedules, start, dates)
 	return schedules, nil
 }
 
+// GraduatedPayments 按产品的阶梯供款配置计算每期供款，未配置阶梯时返回 nil。
+// 显式分段 PaymentSteps 优先；否则按 StepRate/StepInterval 几何递增，首期供款由现值等于本金反解。
+// residual 为最后一期额外归还的尾款（气球贷），没有时传 0
+func GraduatedPayments(principal Decimal, periods int64, rate Decimal, residual Decimal, product *Product) []Decimal {
+	return graduatedPayments(principal, periods, 0, rate, residual, product)
+}
+
+// graduatedPayments offset 为已经摊还过的期数，从第 offset+1 个摊还期开始计算剩余 periods 期的阶梯供款，
+// 用于重定价等场景下按剩余期限重算
+func graduatedPayments(principal Decimal, periods, offset int64, rate Decimal, residual Decimal, product *Product) []Decimal {
+	v := one.Div(one.Add(rate))
+	payments := make([]Decimal, 0, periods)
+	switch {
+	case len(product.PaymentSteps) > 0:
+		balance := principal
+		skip := offset
+		for _, step := range product.PaymentSteps {
+			for k := 0; k < step.Periods && int64(len(payments)) < periods; k++ {
+				if skip > 0 {
+					skip--
+					continue
+				}
+				payments = append(payments, step.Amount)
+				balance = balance.Mul(one.Add(rate)).Sub(step.Amount)
+			}
+		}
+		if rest := periods - int64(len(payments)); rest > 0 {
+			pwt := BalloonPayment(balance, rest, rate, residual)
+			for k := int64(0); k < rest; k++ {
+				payments = append(payments, pwt)
+			}
+		}
+		return payments
+	case product.StepRate.IsPositive() && product.StepInterval > 0:
+		growth := one.Add(product.StepRate)
+		factors := make([]Decimal, periods)
+		pv := decimal.Zero
+		for k := int64(0); k < periods; k++ {
+			factors[k] = growth.Pow(decimal.NewFromInt((k + offset) / int64(product.StepInterval)))
+			pv = pv.Add(factors[k].Mul(v.Pow(decimal.NewFromInt(k + 1))))
+		}
+		first := principal.Sub(residual.Mul(v.Pow(decimal.NewFromInt(periods)))).Div(pv)
+		for k := int64(0); k < periods; k++ {
+			payments = append(payments, first.Mul(factors[k]))
+		}
+		return payments
+	default:
+		return nil
+	}
+}
+
+// BalloonPayment 计算带尾款的等额本息每期供款：摊还 periods 期后剩余 residual 在最后一期一并归还
+func BalloonPayment(principal Decimal, periods int64, rate Decimal, residual Decimal) Decimal {
+	base1rn := rate.Add(one).Pow(decimal.NewFromInt(periods))
+	numerator := principal.Mul(base1rn).Sub(residual).Mul(rate)
+	denominator := base1rn.Sub(one)
+	return numerator.Div(denominator)
+}
+
 func EqualPrincipalPayment(principal Decimal, periods int64, rate Decimal) Decimal {
 	p := principal.Div(decimal.NewFromInt(periods))
 	i := principal.Mul(rate)
@@ -66,37 +328,182 @@ func EqualPrincipalPayment(principal Decimal, periods int64, rate Decimal) Decim
 
 func EqualPrincipalSchedule(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
 	schedules := make([]Schedule, 0, periods)
-	nextDate := func(t time.Time) time.Time {
-		n, _ := NextPeriodDate(t, product.PeriodType, product.RollConvention)
-		return n
+	start := cfg.Clock.Now()
+	dates, stub, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
 	}
-	t := cfg.Clock.Now()
-	r, err := AnnualToPeriodRate(product.Interest, product.PeriodType, product.DayCountConv)
+	r, err := product.periodRate()
 	if err != nil {
 		return nil, err
 	}
 	g := int64(product.GraceTerm)
 	p := principal.Div(decimal.NewFromInt(periods - g))
+	switch {
+	case product.BalloonTerm > int(periods-g):
+		p = principal.Div(decimal.NewFromInt(int64(product.BalloonTerm)))
+	case product.BalloonRatio.IsPositive():
+		p = principal.Sub(principal.Mul(product.BalloonRatio)).Div(decimal.NewFromInt(periods - g))
+	}
+	prev := start
 	for i := int64(1); i <= periods; i++ {
-		t = nextDate(t)
-		fees := make([]Fee, len(product.Fees))
-		copy(fees, product.Fees)
+		t := dates[i-1].Due
 		id := idGenerator()
-		for j := 0; j < len(fees); j++ {
-			fees[j].ID = idGenerator()
-			fees[j].Status = FeeStatusUnPaid
-			fees[j].ScheduleId = id
+		fees := scheduleFees(product, id, idGenerator)
+		pi := decimal.Zero
+		if i > g {
+			pi = p
+			if i == periods {
+				// 最后一期结清剩余本金（含气球贷尾款）
+				pi = principal
+			}
 		}
-		if i <= g {
-			interest := principal.Mul(r)
-			s := NewSchedule(id, loanId, int(i), t, decimal.Zero, interest, fees)
-			schedules = append(schedules, *s)
-			continue
+		// 利息按本期期初余额计算
+		interest, err := periodInterest(principal, r, prev, dates[i-1].Accrual, product, i == 1 && stub)
+		if err != nil {
+			return nil, err
 		}
-		principal = principal.Sub(p)
-		interest := principal.Mul(r)
+		prev = dates[i-1].Accrual
+		principal = principal.Sub(pi)
+		s := NewSchedule(id, loanId, int(i), t, pi, interest, fees)
+		schedules = append(schedules, *s)
+	}
+	stampAccrual(schedules, start, dates)
+	return schedules, nil
+}
+
+// InterestOnlySchedule 生成先息后本计划：每期按全部剩余本金计息，本金在最后一期一次性归还
+func InterestOnlySchedule(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, periods)
+	start := cfg.Clock.Now()
+	dates, stub, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
+	}
+	r, err := product.periodRate()
+	if err != nil {
+		return nil, err
+	}
+	prev := start
+	for i := int64(1); i <= periods; i++ {
+		t := dates[i-1].Due
+		id := idGenerator()
+		fees := scheduleFees(product, id, idGenerator)
+		interest, err := periodInterest(principal, r, prev, dates[i-1].Accrual, product, i == 1 && stub)
+		if err != nil {
+			return nil, err
+		}
+		prev = dates[i-1].Accrual
+		p := decimal.Zero
+		if i == periods {
+			p = principal
+		}
+		s := NewSchedule(id, loanId, int(i), t, p, interest, fees)
+		schedules = append(schedules, *s)
+	}
+	stampAccrual(schedules, start, dates)
+	return schedules, nil
+}
+
+// BulletSchedule 生成到期一次性还本付息计划：只有一期，利息按 DayCountConv 计算整个借款期限
+func BulletSchedule(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	start := cfg.Clock.Now()
+	dates, _, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
+	}
+	maturity := dates[len(dates)-1]
+	ratio, err := product.yearFraction(start, maturity.Accrual)
+	if err != nil {
+		return nil, err
+	}
+	interest := principal.Mul(product.Interest).Mul(ratio)
+	id := idGenerator()
+	fees := scheduleFees(product, id, idGenerator)
+	s := NewSchedule(id, loanId, 1, maturity.Due, principal, interest, fees)
+	s.AccrualStart, s.AccrualEnd = start, maturity.Accrual
+	return []Schedule{*s}, nil
+}
+
+// FlatRateSchedule 生成等本等息计划：每期利息按合同本金计算，本金均摊。
+// 名义利率低估了实际成本，披露用的年化利率见 FlatRateAPR
+func FlatRateSchedule(loanId int64, principal Decimal, periods int64, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, periods)
+	// 等本等息按期收取固定利息，不规则首期不单独计息
+	start := cfg.Clock.Now()
+	dates, _, err := scheduleDates(start, periods, product)
+	if err != nil {
+		return nil, err
+	}
+	r, err := product.periodRate()
+	if err != nil {
+		return nil, err
+	}
+	interest := principal.Mul(r)
+	p := principal.Div(decimal.NewFromInt(periods))
+	for i := int64(1); i <= periods; i++ {
+		t := dates[i-1].Due
+		id := idGenerator()
+		fees := scheduleFees(product, id, idGenerator)
 		s := NewSchedule(id, loanId, int(i), t, p, interest, fees)
 		schedules = append(schedules, *s)
 	}
+	stampAccrual(schedules, start, dates)
+	return schedules, nil
+}
+
+// FlatRateAPR 按 IRR 法把等本等息计划折算为年化利率（名义与实际），放款本金为第 0 期流出，
+// 之后每期流入本金和利息
+func FlatRateAPR(principal Decimal, schedules []Schedule, product *Product) (APR, error) {
+	flows := make([]Decimal, 0, len(schedules)+1)
+	flows = append(flows, principal.Neg())
+	for _, s := range schedules {
+		flows = append(flows, s.Principal.Add(s.Interest))
+	}
+	pr, err := IRR(flows)
+	if err != nil {
+		return APR{}, err
+	}
+	n, err := periodsPerYear(product.PeriodType, product.PeriodLength)
+	if err != nil {
+		return APR{}, err
+	}
+	return annualizeRate(pr, n), nil
+}
+
+// CustomSchedule 按约定的还款日和本金生成计划，利息按 Product.Interest 和 DayCountConv 在相邻两个实际日期之间计算
+func CustomSchedule(loanId int64, principal Decimal, start time.Time, plan []PlanItem, product *Product, idGenerator IDGenerator) ([]Schedule, error) {
+	if len(plan) == 0 {
+		return nil, ErrInvalidPlan
+	}
+	sum := decimal.Zero
+	prev := start
+	for _, item := range plan {
+		if item.Principal.IsNegative() || CompareDate(item.DueDate, prev) <= 0 {
+			return nil, ErrInvalidPlan
+		}
+		sum = sum.Add(item.Principal)
+		prev = item.DueDate
+	}
+	if !sum.Equal(principal) {
+		return nil, ErrInvalidPlan
+	}
+
+	schedules := make([]Schedule, 0, len(plan))
+	prev = start
+	for i, item := range plan {
+		ratio, err := product.yearFraction(prev, item.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		interest := principal.Mul(product.Interest).Mul(ratio)
+		id := idGenerator()
+		fees := scheduleFees(product, id, idGenerator)
+		s := NewSchedule(id, loanId, i+1, item.DueDate, item.Principal, interest, fees)
+		s.AccrualStart, s.AccrualEnd = prev, item.DueDate
+		schedules = append(schedules, *s)
+		principal = principal.Sub(item.Principal)
+		prev = item.DueDate
+	}
 	return schedules, nil
 }
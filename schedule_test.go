@@ -0,0 +1,90 @@
+package loancalc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// startTest 以固定时钟（2025-01-15）、无节假日初始化运行时配置，返回 ID 生成器
+func startTest(t *testing.T, c Config) IDGenerator {
+	t.Helper()
+	var id int64
+	gen := func() int64 { id++; return id }
+	c.IDGenerator = gen
+	if c.Holiday == nil {
+		c.Holiday = HolidayFunc(func(time.Time) bool { return false })
+	}
+	if c.Clock == nil {
+		c.Clock = fixedClock(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC))
+	}
+	if err := Start(c); err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestEqualPrincipalInterestOnOpeningBalance(t *testing.T) {
+	gen := startTest(t, Config{})
+	p := &Product{
+		Interest:     decimal.NewFromFloat(0.12),
+		RepayType:    RepayTypeEqualPrincipal,
+		PeriodType:   PeriodMonth,
+		DayCountConv: BONDBASIS,
+	}
+	schedules, err := EqualPrincipalSchedule(1, decimal.NewFromInt(10000), 3, p, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每期利息按期初余额计算：10000、6666.67、3333.33 各计一个月
+	want := []struct{ principal, interest string }{
+		{"3333.33", "100.00"},
+		{"3333.33", "66.67"},
+		{"3333.33", "33.33"},
+	}
+	if len(schedules) != len(want) {
+		t.Fatalf("got %d schedules, want %d", len(schedules), len(want))
+	}
+	for i, w := range want {
+		s := schedules[i]
+		if s.Principal.StringFixed(2) != w.principal || s.Interest.StringFixed(2) != w.interest {
+			t.Errorf("period %d = %s/%s, want %s/%s", s.Period, s.Principal.StringFixed(2), s.Interest.StringFixed(2), w.principal, w.interest)
+		}
+	}
+}
+
+func TestRuleOf78Restrictions(t *testing.T) {
+	gen := startTest(t, Config{})
+	tests := []struct {
+		name    string
+		product Product
+		periods int64
+		rate    float64
+		err     error
+	}{
+		{"flat", Product{RepayType: RepayTypeFlatRate}, 12, 0.12, nil},
+		{"annuity", Product{RepayType: RepayTypeEqualInstallment}, 12, 0.12, nil},
+		{"interest only", Product{RepayType: RepayTypeInterestOnly}, 12, 0.12, ErrUnSupportRepayType},
+		{"annuity with grace", Product{RepayType: RepayTypeEqualInstallment, GraceTerm: 3}, 12, 0.12, ErrUnSupportRepayType},
+		{"negative amortization", Product{RepayType: RepayTypeEqualInstallment}, 60, 0.36, ErrNegativeAmortization},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.product
+			p.Interest = decimal.NewFromFloat(tt.rate)
+			p.PeriodType = PeriodMonth
+			p.DayCountConv = BONDBASIS
+			p.InterestAllocation = AllocationRuleOf78
+			schedules, err := buildSchedules(1, decimal.NewFromInt(12000), tt.periods, &p, gen)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			for _, s := range schedules {
+				if s.Principal.IsNegative() {
+					t.Errorf("period %d principal %s is negative", s.Period, s.Principal.StringFixed(2))
+				}
+			}
+		})
+	}
+}
@@ -28,6 +28,15 @@ type ProductStatues string
 
 type LoanStatus string
 
+// RepriceRule 浮动利率的重定价日规则
+type RepriceRule string
+
+// StubType 首期不规则（短首期/长首期）的处理方式
+type StubType string
+
+// InterestAllocation 利息在各期之间的分摊方式
+type InterestAllocation string
+
 const (
 	LoanPaid     LoanStatus = "PAID"
 	LoanUnpaid   LoanStatus = "UNPAID"
@@ -40,6 +49,8 @@ const (
 	Following  RollConvention = "FOLLOWING"          //如果是节假日，向后挪
 	Preceding  RollConvention = "PRECEDING"          //如果是节假日，向前挪
 	ModFollow  RollConvention = "MODIFIED_FOLLOWING" //如果是节假日，向后挪，但如果跨月就向前挪
+	ModPrecede RollConvention = "MODIFIED_PRECEDING" //如果是节假日，向前挪，但如果跨月就向后挪
+	Nearest    RollConvention = "NEAREST"            //如果是节假日，挪到最近的营业日，距离相同时向后挪
 )
 
 const (
@@ -49,18 +60,49 @@ const (
 	FIXED       DayCountConv = "FIXED"
 	ISDA        DayCountConv = "ISDA"
 	AFB         DayCountConv = "AFB"
+	// 以下为结构化贷款、债券类产品合同中常见的惯例
+	ICMA         DayCountConv = "ICMA"         // Act/Act ICMA：实际天数/(频率×参考期天数)
+	ACT365L      DayCountConv = "ACT365L"      // Act/365L：期间含 2 月 29 日时分母为 366
+	EUROBONDPLUS DayCountConv = "EUROBONDPLUS" // 30E+/360：到期日为 31 日时顺延到下月 1 日
+	NL365        DayCountConv = "NL365"        // NL/365：实际天数剔除 2 月 29 日/365
+	BUS252       DayCountConv = "BUS252"       // BUS/252：工作日天数/252，工作日按 HolidayProvider 判断
 )
 
 const (
 	RepayTypeEqualPrincipal   RepayType = "EQUAL_PRINCIPAL"   // 等额本金
 	RepayTypeEqualInstallment RepayType = "EQUAL_INSTALLMENT" // 等额本息
+	RepayTypeInterestOnly     RepayType = "INTEREST_ONLY"     // 先息后本
+	RepayTypeBullet           RepayType = "BULLET"            // 到期一次性还本付息
+	RepayTypeFlatRate         RepayType = "FLAT_RATE"         // 等本等息
+	RepayTypeCustom           RepayType = "CUSTOM"            // 自定义还款计划，按约定日期和本金还款
+)
+
+const (
+	RepriceAnniversary RepriceRule = "ANNIVERSARY" // 按放款日对应日重定价
+	RepriceJanFirst    RepriceRule = "JAN_FIRST"   // 每年 1 月 1 日重定价
+)
+
+const (
+	StubShort StubType = "SHORT" // 首期到最近的固定还款日，短于一期
+	StubLong  StubType = "LONG"  // 首期跳过最近的固定还款日，长于一期
+	StubMerge StubType = "MERGE" // 首期不足半期时并入下一期（长首期），否则为短首期
+)
+
+const (
+	AllocationActuarial InterestAllocation = "ACTUARIAL"  // 按剩余本金逐期计息（默认）
+	AllocationRuleOf78  InterestAllocation = "RULE_OF_78" // 78 法则：总利息按期数倒序的数字和分摊
 )
 
 const (
-	PeriodDay    PeriodType = "DAY"
-	PeriodBiWeek PeriodType = "BI_WEEK"
-	PeriodMonth  PeriodType = "MONTH"
-	PeriodYear   PeriodType = "YEAR"
+	PeriodDay      PeriodType = "DAY"
+	PeriodBiWeek   PeriodType = "BI_WEEK"
+	PeriodMonth    PeriodType = "MONTH"
+	PeriodYear     PeriodType = "YEAR"
+	PeriodWeek     PeriodType = "WEEK"
+	PeriodQuarter  PeriodType = "QUARTER"
+	PeriodHalfYear PeriodType = "HALF_YEAR"
+	PeriodNDays    PeriodType = "N_DAYS"   // 每 N 天一期，N 由 Product.PeriodLength 指定
+	PeriodNMonths  PeriodType = "N_MONTHS" // 每 N 个月一期，N 由 Product.PeriodLength 指定
 )
 
 const (